@@ -9,8 +9,10 @@ import (
 	"log/slog"
 	"os"
 
+	"github.com/mbarrin/gwarr/internal/pkg/config"
 	"github.com/mbarrin/gwarr/internal/pkg/server"
 	"github.com/mbarrin/gwarr/internal/pkg/slack"
+	"github.com/mbarrin/gwarr/internal/pkg/sonarr"
 )
 
 func main() {
@@ -19,6 +21,7 @@ func main() {
 	sonarr := flag.Bool("sonarr", true, "run the sonarr endpoint")
 	debug := flag.Bool("debug", false, "enable debug logging")
 	redisAddr := flag.String("redis-addr", "localhost:6379", "override the redis address")
+	configPath := flag.String("config", "", "path to a config file defining routes")
 	flag.Parse()
 
 	logLevel := slog.LevelInfo
@@ -29,18 +32,36 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))
 	slog.SetDefault(logger)
 
-	channelID, slackBotToken, err := checkEnv()
+	conf, err := loadConfig(*configPath)
 	if err != nil {
 		slog.With("package", "main").Error(err.Error())
 		os.Exit(1)
 	}
 
-	sc, err := slack.New(channelID, slackBotToken, *redisAddr)
+	signingSecret, home := os.LookupEnv("GWARR_SLACK_SIGNING_SECRET")
+	if !home {
+		slog.With("package", "main").Info("GWARR_SLACK_SIGNING_SECRET not set, App Home disabled")
+	}
+
+	slackBotToken, err := checkToken(conf.NeedsToken() || home)
+	if err != nil {
+		slog.With("package", "main").Error(err.Error())
+		os.Exit(1)
+	}
+
+	sc, err := slack.New(conf.Routes, slackBotToken, signingSecret, *redisAddr)
 	if err != nil {
 		os.Exit(1)
 	}
 
-	err = server.Start(*port, *sc, *radarr, *sonarr)
+	if client := sonarrClient(); client != nil {
+		sc.CheckSeasons(client)
+		sc.ShowAirings(client)
+	}
+
+	sc.StartDigests()
+
+	err = server.Start(*port, *sc, *radarr, *sonarr, home)
 	if err != nil {
 		os.Exit(1)
 	}
@@ -48,20 +69,49 @@ func main() {
 	slog.With("package", "main").Info("GWARR is running")
 }
 
-func checkEnv() (string, string, error) {
+// loadConfig reads the config file if there is one, otherwise it
+// sends everything to the channel or incoming webhook in the environment
+func loadConfig(path string) (*config.Config, error) {
+	if path != "" {
+		conf, err := config.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		sonarr.SetFormats(conf.Formats)
+		return conf, nil
+	}
+
 	channelID, channelIDExists := os.LookupEnv("GWARR_SLACK_CHANNEL_ID")
-	if !channelIDExists {
-		slog.With("package", "main").Error("Missing GWARR_SLACK_CHANNEL_ID")
+	webhookURL, webhookURLExists := os.LookupEnv("GWARR_SLACK_WEBHOOK_URL")
+	if !channelIDExists && !webhookURLExists {
+		slog.With("package", "main").Error("Missing GWARR_SLACK_CHANNEL_ID or GWARR_SLACK_WEBHOOK_URL")
+		return nil, errors.New("missing required tokens. Check logs")
 	}
 
+	return config.Default(channelID, webhookURL), nil
+}
+
+// checkToken reads the bot token, which is only needed to post with
+// chat.postMessage or to publish the App Home tab
+func checkToken(needed bool) (string, error) {
 	slackBotToken, slackBotTokenExists := os.LookupEnv("GWARR_SLACK_BOT_TOKEN")
-	if !slackBotTokenExists {
+	if !slackBotTokenExists && needed {
 		slog.With("package", "main").Error("Missing GWARR_SLACK_BOT_TOKEN")
+		return "", errors.New("missing required tokens. Check logs")
 	}
 
-	if channelIDExists && slackBotTokenExists {
-		return channelID, slackBotToken, nil
+	return slackBotToken, nil
+}
+
+// sonarrClient connects to the Sonarr API if it has been configured, so
+// completed seasons can be checked against the episodes Sonarr monitors
+func sonarrClient() *sonarr.Client {
+	url, urlExists := os.LookupEnv("GWARR_SONARR_URL")
+	key, keyExists := os.LookupEnv("GWARR_SONARR_API_KEY")
+	if !urlExists || !keyExists {
+		slog.With("package", "main").Info("GWARR_SONARR_URL or GWARR_SONARR_API_KEY not set, seasons are only checked against season packs")
+		return nil
 	}
 
-	return "", "", errors.New("missing required tokens. Check logs")
+	return sonarr.NewClient(url, key)
 }
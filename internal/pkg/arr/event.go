@@ -0,0 +1,169 @@
+package arr
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+// Event defines a webhook from any *arr in the same shape, so notifiers
+// don't need to know which *arr sent it
+type Event struct {
+	// Source is the *arr that sent the webhook, like "radarr"
+	Source string
+	// Instance is the name of the instance that sent the webhook, which
+	// older *arrs don't send
+	Instance string
+	// Kind is the type of event. Sonarr's ImportComplete is a Download of
+	// the whole release
+	Kind arrhook.EventKind
+	// Name is the eventType as it was sent, see arrhook's EventName
+	Name string
+	// ID is the item the event is about: a movie, an episode or a series
+	ID int
+	// Title is how the event is titled in messages
+	Title string
+	// URL links to the subject in the *arr, and DeepURL to the part of it
+	// the event is about, like the season of an episode
+	URL     string
+	DeepURL string
+	// ReleaseDate is when the movie or episode was released
+	ReleaseDate string
+	Upgrade     bool
+	Subject     Subject
+	Release     *Release
+	Files       []File
+
+	// The details of particular events, which are empty for the rest
+	DownloadID         string
+	DownloadClient     string
+	DownloadClientType string
+	CustomFormats      *CustomFormatInfo
+	Languages          []string
+	ImportPaths        *ImportPaths
+	Replaced           []File
+	DeleteReason       string
+	Renames            []RenamedFile
+	Health             *Health
+	Update             *Update
+	Interaction        *Interaction
+	Series             *SeriesInfo
+	SeasonPack         bool
+
+	// Received is when gwarr received the webhook, and Added is when the
+	// *arr imported its files, if it says
+	Received time.Time
+	Added    time.Time
+	Raw      json.RawMessage
+}
+
+// Subject defines the movie or series an event is about
+type Subject struct {
+	ID       int
+	Title    string
+	Year     int
+	IMDBID   string
+	TMDBID   int
+	TVDBID   int
+	URL      string
+	Path     string
+	Tags     []string
+	Episodes []Episode
+}
+
+// Source is implemented by the webhook types of each *arr, which are
+// arrhook's payloads
+type Source interface {
+	arrhook.Payload
+	Event() *Event
+}
+
+// Receive normalizes a parsed webhook into an event
+func Receive(s Source, raw []byte, t time.Time) *Event {
+	e := s.Event()
+	e.Raw = raw
+	e.Received = t
+
+	for _, f := range e.Files {
+		added, err := time.Parse(time.RFC3339, f.DateAdded)
+		if err == nil && added.After(e.Added) {
+			e.Added = added
+		}
+	}
+
+	return e
+}
+
+// RouteType returns the event type routes match against. Upgrades are
+// their own type so they can be sent somewhere other than first downloads
+func RouteType(eventType string, upgrade bool) string {
+	if eventType == "Download" && upgrade {
+		return "Upgrade"
+	}
+	return eventType
+}
+
+// ImportComplete returns true for Sonarr's ImportComplete, the Download of
+// a whole release sent once every file of it is imported
+func (e *Event) ImportComplete() bool {
+	return e.Name == arrhook.EventImportComplete.String()
+}
+
+// Type returns the name of the event's kind, like "Grab". Kinds gwarr
+// doesn't know keep the name they were sent with
+func (e *Event) Type() string {
+	if e.Kind == arrhook.EventUnknown && e.Name != "" {
+		return e.Name
+	}
+	return e.Kind.String()
+}
+
+// RouteType returns the event type routes match against, see RouteType
+func (e *Event) RouteType() string { return RouteType(e.Type(), e.Upgrade) }
+
+// Quality returns the quality of a grabbed release, or of the files an
+// event is about
+func (e *Event) Quality() string {
+	if e.Kind == arrhook.EventGrab && e.Release != nil {
+		return e.Release.Quality
+	}
+	if len(e.Files) > 0 {
+		return e.Files[0].Quality
+	}
+	return ""
+}
+
+// ReleaseGroup returns the group of a grabbed release, or of the files an
+// event is about
+func (e *Event) ReleaseGroup() string {
+	if e.Kind == arrhook.EventGrab && e.Release != nil {
+		return e.Release.ReleaseGroup
+	}
+	if len(e.Files) > 0 {
+		return e.Files[0].ReleaseGroup
+	}
+	return ""
+}
+
+// Size returns the size of a grabbed release, or of all the files an
+// event is about
+func (e *Event) Size() int {
+	if e.Kind == arrhook.EventGrab && e.Release != nil {
+		return e.Release.Size
+	}
+
+	size := 0
+	for _, f := range e.Files {
+		size += f.Size
+	}
+	return size
+}
+
+// Score returns the custom format score of a release, if it has one
+func (e *Event) Score() *int {
+	if e.CustomFormats == nil {
+		return nil
+	}
+	return &e.CustomFormats.CustomFormatScore
+}
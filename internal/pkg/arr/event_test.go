@@ -0,0 +1,68 @@
+package arr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+// source is a minimal *arr webhook
+type source struct {
+	files []File
+}
+
+func (s source) Kind() arrhook.EventKind { return arrhook.EventDownload }
+func (s source) Service() string         { return "test" }
+func (s source) Instance() string        { return "" }
+
+func (s source) Event() *Event {
+	return &Event{Source: "test", Files: s.files}
+}
+
+func TestReceive(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	s := source{files: []File{{DateAdded: "2024-03-01T08:00:00Z"}, {DateAdded: "2024-03-01T08:30:00Z"}, {DateAdded: "soon"}}}
+
+	e := Receive(s, []byte(`{}`), now)
+
+	assert.Equal(t, "test", e.Source)
+	assert.Equal(t, now, e.Received)
+	assert.Equal(t, time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC), e.Added)
+	assert.JSONEq(t, `{}`, string(e.Raw))
+
+	e = Receive(source{}, nil, now)
+	assert.True(t, e.Added.IsZero())
+}
+
+func TestRouteType(t *testing.T) {
+	assert.Equal(t, "Upgrade", RouteType("Download", true))
+	assert.Equal(t, "Download", RouteType("Download", false))
+	assert.Equal(t, "Grab", RouteType("Grab", true))
+
+	e := Event{Kind: arrhook.EventDownload, Upgrade: true}
+	assert.Equal(t, "Download", e.Type())
+	assert.Equal(t, "Upgrade", e.RouteType())
+
+	e = Event{Kind: arrhook.EventUnknown, Name: "MovieRetitled"}
+	assert.Equal(t, "MovieRetitled", e.Type())
+}
+
+func TestRelease(t *testing.T) {
+	grab := Event{Kind: arrhook.EventGrab, Release: &Release{Quality: "WEBDL-1080p", ReleaseGroup: "legit", Size: 100}}
+	assert.Equal(t, "WEBDL-1080p", grab.Quality())
+	assert.Equal(t, "legit", grab.ReleaseGroup())
+	assert.Equal(t, 100, grab.Size())
+
+	download := Event{Kind: arrhook.EventDownload, Release: grab.Release, Files: []File{{Quality: "WEBDL-2160p", ReleaseGroup: "NTb", Size: 10}, {Quality: "WEBDL-2160p", Size: 20}}}
+	assert.Equal(t, "WEBDL-2160p", download.Quality())
+	assert.Equal(t, "NTb", download.ReleaseGroup())
+	assert.Equal(t, 30, download.Size())
+
+	assert.Empty(t, (&Event{Kind: arrhook.EventTest}).Quality())
+	assert.Nil(t, grab.Score())
+	grab.CustomFormats = &CustomFormatInfo{CustomFormatScore: 25}
+	assert.Equal(t, 25, *grab.Score())
+}
@@ -0,0 +1,76 @@
+/*
+Package arr defines the payload types shared by every *arr, and the event
+each webhook is normalized into before it is sent anywhere. The payload
+types are arrhook's, so gwarr parses webhooks the same way as the public
+library
+*/
+package arr
+
+import "github.com/mbarrin/gwarr/pkg/arrhook"
+
+// ParseError defines a custom error type for failing to turn a webhook
+// into an *arr's data struct
+type ParseError = arrhook.ParseError
+
+type (
+	Release          = arrhook.Release
+	File             = arrhook.File
+	RenamedFile      = arrhook.RenamedFile
+	MediaInfo        = arrhook.MediaInfo
+	Language         = arrhook.Language
+	DeletedFiles     = arrhook.DeletedFiles
+	Health           = arrhook.Health
+	Update           = arrhook.Update
+	CustomFormatInfo = arrhook.CustomFormatInfo
+	CustomFormat     = arrhook.CustomFormat
+	DownloadInfo     = arrhook.DownloadInfo
+	StatusMessage    = arrhook.StatusMessage
+	Movie            = arrhook.Movie
+	RemoteMovie      = arrhook.RemoteMovie
+	Series           = arrhook.Series
+	Season           = arrhook.Season
+	Episode          = arrhook.Episode
+	Tags             = arrhook.Tags
+)
+
+// Interaction defines a download that needs someone to import it by hand
+type Interaction struct {
+	ReleaseTitle string
+	Status       string
+	Messages     []string
+	QueueURL     string
+}
+
+// NewInteraction describes why a ManualInteractionRequired download is
+// blocked, from what the *arr sends about it
+func NewInteraction(appURL string, info *DownloadInfo, status string, messages []StatusMessage) *Interaction {
+	i := Interaction{Status: status, QueueURL: appURL + "/activity/queue"}
+
+	if info != nil {
+		i.ReleaseTitle = info.Title
+	}
+
+	for _, m := range messages {
+		if len(m.Messages) == 0 {
+			i.Messages = append(i.Messages, m.Title)
+		}
+		i.Messages = append(i.Messages, m.Messages...)
+	}
+
+	return &i
+}
+
+// SeriesInfo defines the metadata of a series added to or deleted from an *arr
+type SeriesInfo struct {
+	Year         int
+	Network      string
+	Seasons      int
+	Monitored    *bool
+	FilesDeleted bool
+}
+
+// ImportPaths defines where an imported release was moved from and to
+type ImportPaths struct {
+	Source      string
+	Destination string
+}
@@ -0,0 +1,258 @@
+/*
+Package config defines the optional gwarr configuration file
+*/
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mbarrin/gwarr/internal/pkg/schedule"
+)
+
+// Config defines the structure of the configuration file
+type Config struct {
+	Routes  []Route           `json:"routes"`
+	Formats map[string]string `json:"formats,omitempty"`
+}
+
+// Route defines a Slack channel and the events that are sent to it
+type Route struct {
+	Name         string            `json:"name"`
+	Channel      string            `json:"channel,omitempty"`
+	Webhook      string            `json:"webhook,omitempty"`
+	Services     []string          `json:"services,omitempty"`
+	Events       []string          `json:"events,omitempty"`
+	OnDelete     string            `json:"onDelete,omitempty"`
+	Ops          bool              `json:"ops,omitempty"`
+	SkipUpgrades bool              `json:"skipUpgrades,omitempty"`
+	MinScore     *int              `json:"minScore,omitempty"`
+	MaxScore     *int              `json:"maxScore,omitempty"`
+	HideFields   []string          `json:"hideFields,omitempty"`
+	DeepLinks    bool              `json:"deepLinks,omitempty"`
+	ThreadSeries bool              `json:"threadSeries,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	SkipTags     []string          `json:"skipTags,omitempty"`
+	Mentions     map[string]string `json:"mentions,omitempty"`
+	DigestOnly   bool              `json:"digestOnly,omitempty"`
+	Digest       *Digest           `json:"digest,omitempty"`
+}
+
+// Digest defines when a summary is posted to a route and how it is laid out
+type Digest struct {
+	Schedule   string   `json:"schedule"`
+	GroupBy    string   `json:"groupBy,omitempty"`
+	StuckAfter Duration `json:"stuckAfter,omitempty"`
+}
+
+// Duration is a time.Duration that is written as a string like "6h"
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	d.Duration, err = time.ParseDuration(s)
+	return err
+}
+
+// MarshalJSON writes a duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Load reads and validates a configuration file
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := Config{}
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, c.validate()
+}
+
+// Default creates a configuration that sends everything to one channel,
+// through an incoming webhook if one is given. Its route is an ops route,
+// so it gets ops events too. Routes from a config file have to opt in
+func Default(channel string, webhook string) *Config {
+	return &Config{Routes: []Route{{Name: "default", Channel: channel, Webhook: webhook, Ops: true}}}
+}
+
+// opsEvents are about the *arrs themselves rather than media, so they
+// are only sent to ops routes unless a route asks for them by name
+var opsEvents = []string{"ApplicationUpdate"}
+
+// seriesTypes are the types of series Sonarr has, which each have their
+// own episode title format
+var seriesTypes = []string{"standard", "daily", "anime"}
+
+// grabFields are the optional details of a grab message that a route
+// can hide
+var grabFields = []string{"size", "indexer", "client", "release"}
+
+func (c *Config) validate() error {
+	if len(c.Routes) == 0 {
+		return errors.New("no routes configured")
+	}
+
+	for seriesType := range c.Formats {
+		if !slices.Contains(seriesTypes, seriesType) {
+			return fmt.Errorf("format for unknown series type %s", seriesType)
+		}
+	}
+
+	names := map[string]bool{}
+	for i, r := range c.Routes {
+		if r.Name == "" {
+			return fmt.Errorf("route %d has no name", i)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("route %s is defined more than once", r.Name)
+		}
+		names[r.Name] = true
+
+		if r.Channel == "" && r.Webhook == "" {
+			return fmt.Errorf("route %s has no channel or webhook", r.Name)
+		}
+
+		switch r.OnDelete {
+		case "", "post":
+		case "strike", "delete", "thread":
+			if r.Webhook != "" {
+				return fmt.Errorf("route %s can't %s messages through a webhook", r.Name, r.OnDelete)
+			}
+		default:
+			return fmt.Errorf("route %s has unknown onDelete %s", r.Name, r.OnDelete)
+		}
+
+		if r.ThreadSeries && r.Webhook != "" {
+			return fmt.Errorf("route %s can't thread series through a webhook", r.Name)
+		}
+
+		if r.MinScore != nil && r.MaxScore != nil && *r.MinScore > *r.MaxScore {
+			return fmt.Errorf("route %s has a minScore above its maxScore", r.Name)
+		}
+
+		for _, f := range r.HideFields {
+			if !slices.Contains(grabFields, f) {
+				return fmt.Errorf("route %s has unknown field %s", r.Name, f)
+			}
+		}
+
+		for tag, id := range r.Mentions {
+			if !strings.HasPrefix(id, "U") && !strings.HasPrefix(id, "W") && !strings.HasPrefix(id, "S") {
+				return fmt.Errorf("route %s mentions %s for tag %s, which isn't a user or user group ID", r.Name, id, tag)
+			}
+		}
+
+		if r.DigestOnly && r.Digest == nil {
+			return fmt.Errorf("route %s is digest only but has no digest", r.Name)
+		}
+
+		if r.Digest != nil {
+			_, err := schedule.Parse(r.Digest.Schedule)
+			if err != nil {
+				return fmt.Errorf("route %s: %w", r.Name, err)
+			}
+
+			switch r.Digest.GroupBy {
+			case "", "service", "event":
+			default:
+				return fmt.Errorf("route %s has unknown digest grouping %s", r.Name, r.Digest.GroupBy)
+			}
+		}
+	}
+
+	return nil
+}
+
+// NeedsToken returns true if any route posts as a bot rather than
+// through an incoming webhook
+func (c *Config) NeedsToken() bool {
+	for _, r := range c.Routes {
+		if r.Webhook == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns true if an event from a service should be sent to the route
+func (r Route) Matches(service string, eventType string) bool {
+	if slices.Contains(opsEvents, eventType) && !r.Ops && !slices.Contains(r.Events, eventType) {
+		return false
+	}
+
+	if eventType == "Upgrade" && r.SkipUpgrades {
+		return false
+	}
+
+	if len(r.Services) > 0 && !slices.Contains(r.Services, service) {
+		return false
+	}
+
+	if len(r.Events) > 0 && !slices.Contains(r.Events, eventType) {
+		// Upgrades are downloads too, so routes asking for either get them
+		return eventType == "Upgrade" && slices.Contains(r.Events, "Download")
+	}
+
+	return true
+}
+
+// MatchesScore returns true if a release's custom format score is within
+// the route's limits. Events without a score are never filtered out
+func (r Route) MatchesScore(score *int) bool {
+	if score == nil {
+		return true
+	}
+
+	if r.MinScore != nil && *score < *r.MinScore {
+		return false
+	}
+
+	return r.MaxScore == nil || *score <= *r.MaxScore
+}
+
+// Shows returns true if a route hasn't hidden an optional field
+func (r Route) Shows(field string) bool {
+	return !slices.Contains(r.HideFields, field)
+}
+
+// MatchesTags returns true if an item's tags are wanted by the route. A
+// route listing tags only gets items with at least one of them
+func (r Route) MatchesTags(tags []string) bool {
+	for _, t := range tags {
+		if slices.Contains(r.SkipTags, t) {
+			return false
+		}
+	}
+
+	if len(r.Tags) == 0 {
+		return true
+	}
+
+	for _, t := range tags {
+		if slices.Contains(r.Tags, t) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatches(t *testing.T) {
+	tests := map[string]struct {
+		route    Route
+		expected bool
+	}{
+		"everything":      {route: Route{}, expected: true},
+		"service matches": {route: Route{Services: []string{"radarr"}}, expected: true},
+		"service differs": {route: Route{Services: []string{"sonarr"}}, expected: false},
+		"event matches":   {route: Route{Events: []string{"Grab", "Download"}}, expected: true},
+		"event differs":   {route: Route{Events: []string{"Grab"}}, expected: false},
+	}
+
+	for name, tc := range tests {
+		assert.Equal(t, tc.expected, tc.route.Matches("radarr", "Download"), name)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		config   Config
+		expected string
+	}{
+		"valid":          {config: Config{Routes: []Route{{Name: "a", Channel: "c1", Digest: &Digest{Schedule: "0 9 * * *"}}}}, expected: ""},
+		"no routes":      {config: Config{}, expected: "no routes configured"},
+		"bad format":     {config: Config{Routes: []Route{{Name: "a", Channel: "c1"}}, Formats: map[string]string{"cartoon": "{title}"}}, expected: "format for unknown series type cartoon"},
+		"no name":        {config: Config{Routes: []Route{{Channel: "c1"}}}, expected: "route 0 has no name"},
+		"duplicate name": {config: Config{Routes: []Route{{Name: "a", Channel: "c1"}, {Name: "a", Channel: "c2"}}}, expected: "route a is defined more than once"},
+		"no channel":     {config: Config{Routes: []Route{{Name: "a"}}}, expected: "route a has no channel or webhook"},
+		"webhook only":   {config: Config{Routes: []Route{{Name: "a", Webhook: "https://hooks.slack.com/services/a"}}}, expected: ""},
+		"bad onDelete":   {config: Config{Routes: []Route{{Name: "a", Channel: "c1", OnDelete: "shred"}}}, expected: "route a has unknown onDelete shred"},
+		"webhook strike": {config: Config{Routes: []Route{{Name: "a", Webhook: "https://hooks.slack.com/services/a", OnDelete: "strike"}}}, expected: "route a can't strike messages through a webhook"},
+		"bad scores":     {config: Config{Routes: []Route{{Name: "a", Channel: "c1", MinScore: score(10), MaxScore: score(0)}}}, expected: "route a has a minScore above its maxScore"},
+		"bad field":      {config: Config{Routes: []Route{{Name: "a", Channel: "c1", HideFields: []string{"size", "colour"}}}}, expected: "route a has unknown field colour"},
+		"bad mention":    {config: Config{Routes: []Route{{Name: "a", Channel: "c1", Mentions: map[string]string{"kids": "alice"}}}}, expected: "route a mentions alice for tag kids, which isn't a user or user group ID"},
+		"webhook thread": {config: Config{Routes: []Route{{Name: "a", Webhook: "https://hooks.slack.com/services/a", ThreadSeries: true}}}, expected: "route a can't thread series through a webhook"},
+		"no digest":      {config: Config{Routes: []Route{{Name: "a", Channel: "c1", DigestOnly: true}}}, expected: "route a is digest only but has no digest"},
+		"bad schedule":   {config: Config{Routes: []Route{{Name: "a", Channel: "c1", Digest: &Digest{Schedule: "daily"}}}}, expected: `route a: cron expression "daily" must have 5 fields`},
+		"bad grouping":   {config: Config{Routes: []Route{{Name: "a", Channel: "c1", Digest: &Digest{Schedule: "0 9 * * *", GroupBy: "size"}}}}, expected: "route a has unknown digest grouping size"},
+	}
+
+	for name, tc := range tests {
+		err := tc.config.validate()
+		if tc.expected == "" {
+			assert.NoError(t, err, name)
+		} else {
+			assert.EqualError(t, err, tc.expected, name)
+		}
+	}
+}
+
+func TestMatchesOps(t *testing.T) {
+	tests := map[string]struct {
+		route    Route
+		expected bool
+	}{
+		"ops route":     {route: Route{Ops: true}, expected: true},
+		"media route":   {route: Route{}, expected: false},
+		"asked by name": {route: Route{Events: []string{"ApplicationUpdate"}}, expected: true},
+		"ops filtered":  {route: Route{Ops: true, Services: []string{"sonarr"}}, expected: false},
+		"default route": {route: Default("c1", "").Routes[0], expected: true},
+	}
+
+	for name, tc := range tests {
+		assert.Equal(t, tc.expected, tc.route.Matches("radarr", "ApplicationUpdate"), name)
+	}
+}
+
+func TestMatchesUpgrade(t *testing.T) {
+	tests := map[string]struct {
+		route    Route
+		expected bool
+	}{
+		"everything":    {route: Route{}, expected: true},
+		"downloads":     {route: Route{Events: []string{"Download"}}, expected: true},
+		"upgrades":      {route: Route{Events: []string{"Upgrade"}}, expected: true},
+		"first only":    {route: Route{Events: []string{"Download"}, SkipUpgrades: true}, expected: false},
+		"grabs":         {route: Route{Events: []string{"Grab"}}, expected: false},
+		"skip upgrades": {route: Route{SkipUpgrades: true}, expected: false},
+	}
+
+	for name, tc := range tests {
+		assert.Equal(t, tc.expected, tc.route.Matches("radarr", "Upgrade"), name)
+	}
+}
+
+func TestMatchesScore(t *testing.T) {
+	tests := map[string]struct {
+		route    Route
+		score    *int
+		expected bool
+	}{
+		"no limits":     {route: Route{}, score: score(-50), expected: true},
+		"no score":      {route: Route{MaxScore: score(0)}, score: nil, expected: true},
+		"low score":     {route: Route{MaxScore: score(0)}, score: score(-50), expected: true},
+		"good score":    {route: Route{MaxScore: score(0)}, score: score(100), expected: false},
+		"at minimum":    {route: Route{MinScore: score(100)}, score: score(100), expected: true},
+		"below minimum": {route: Route{MinScore: score(100)}, score: score(99), expected: false},
+	}
+
+	for name, tc := range tests {
+		assert.Equal(t, tc.expected, tc.route.MatchesScore(tc.score), name)
+	}
+}
+
+func score(n int) *int {
+	return &n
+}
+
+func TestMatchesTags(t *testing.T) {
+	tests := map[string]struct {
+		route    Route
+		tags     []string
+		expected bool
+	}{
+		"no filter":    {route: Route{}, tags: []string{"kids"}, expected: true},
+		"tag matches":  {route: Route{Tags: []string{"4k", "kids"}}, tags: []string{"kids"}, expected: true},
+		"tag differs":  {route: Route{Tags: []string{"4k"}}, tags: []string{"kids"}, expected: false},
+		"untagged":     {route: Route{Tags: []string{"4k"}}, tags: nil, expected: false},
+		"skipped":      {route: Route{SkipTags: []string{"kids"}}, tags: []string{"4k", "kids"}, expected: false},
+		"not skipped":  {route: Route{SkipTags: []string{"kids"}}, tags: []string{"4k"}, expected: true},
+		"skip trumped": {route: Route{Tags: []string{"4k"}, SkipTags: []string{"kids"}}, tags: []string{"4k", "kids"}, expected: false},
+	}
+
+	for name, tc := range tests {
+		assert.Equal(t, tc.expected, tc.route.MatchesTags(tc.tags), name)
+	}
+}
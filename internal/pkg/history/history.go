@@ -0,0 +1,194 @@
+/*
+Package history keeps a rolling log of the webhooks gwarr has received
+*/
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+	"github.com/redis/go-redis/v9"
+)
+
+var ctx = context.Background()
+
+const key = "history"
+
+// Entry defines a single event that has been seen
+type Entry struct {
+	Service      string    `json:"service"`
+	ID           int       `json:"id"`
+	Type         string    `json:"type"`
+	Title        string    `json:"title"`
+	URL          string    `json:"url,omitempty"`
+	Quality      string    `json:"quality,omitempty"`
+	ReleaseGroup string    `json:"releaseGroup,omitempty"`
+	Size         int       `json:"size,omitempty"`
+	Upgrade      bool      `json:"upgrade,omitempty"`
+	Check        string    `json:"check,omitempty"`
+	DeleteReason string    `json:"deleteReason,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	Score        *int      `json:"score,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// Key returns the identifier shared by every event for the same item
+func (e Entry) Key() string { return fmt.Sprintf("%s:%d", e.Service, e.ID) }
+
+// RouteType returns the event type used for routing, see arr.RouteType
+func (e Entry) RouteType() string { return arr.RouteType(e.Type, e.Upgrade) }
+
+// NewEntry creates an entry from a webhook
+func NewEntry(e *arr.Event) Entry {
+	entry := Entry{
+		Service:      e.Source,
+		ID:           e.ID,
+		Type:         e.Type(),
+		Title:        e.Title,
+		URL:          e.Subject.URL,
+		Tags:         e.Subject.Tags,
+		Score:        e.Score(),
+		DeleteReason: e.DeleteReason,
+		Time:         e.Received,
+	}
+
+	if e.Kind == arrhook.EventGrab || e.Kind == arrhook.EventDownload {
+		entry.Quality = e.Quality()
+		entry.ReleaseGroup = e.ReleaseGroup()
+		entry.Size = e.Size()
+		entry.Upgrade = e.Upgrade
+	}
+
+	if e.Health != nil {
+		entry.Check = e.Health.Type
+	}
+
+	return entry
+}
+
+// Store defines a capped list of entries kept in Redis, newest first
+type Store struct {
+	redis *redis.Client
+	size  int64
+}
+
+// New creates a store that keeps the latest size entries
+func New(rdb *redis.Client, size int64) *Store {
+	return &Store{redis: rdb, size: size}
+}
+
+// Size returns how many entries the store keeps
+func (s *Store) Size() int64 { return s.size }
+
+// Add records an entry and drops anything older than the store size
+func (s *Store) Add(e Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, key, b)
+		pipe.LTrim(ctx, key, 0, s.size-1)
+		return nil
+	})
+	return err
+}
+
+// Entries returns every stored entry, newest first
+func (s *Store) Entries() ([]Entry, error) {
+	raw, err := s.redis.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(raw))
+	for _, r := range raw {
+		e := Entry{}
+		if err := json.Unmarshal([]byte(r), &e); err != nil {
+			slog.With("package", "history").Error(err.Error())
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// Downloads returns the latest n downloads
+func Downloads(entries []Entry, n int) []Entry {
+	downloads := []Entry{}
+	for _, e := range entries {
+		if len(downloads) == n {
+			break
+		}
+		if e.Type == "Download" {
+			downloads = append(downloads, e)
+		}
+	}
+	return downloads
+}
+
+// Stuck returns items whose latest event is a grab older than threshold
+func Stuck(entries []Entry, now time.Time, threshold time.Duration) []Entry {
+	stuck := []Entry{}
+	for _, e := range Latest(entries) {
+		if e.Type == "Grab" && now.Sub(e.Time) > threshold {
+			stuck = append(stuck, e)
+		}
+	}
+	return stuck
+}
+
+// Health returns health issues that have not been restored, newest
+// first. Each check is only returned once however often it was raised
+func Health(entries []Entry) []Entry {
+	issues := []Entry{}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if e.Type != "Health" && e.Type != "HealthRestored" {
+			continue
+		}
+
+		k := e.Service + ":" + e.Check
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		if e.Type == "Health" {
+			issues = append(issues, e)
+		}
+	}
+	return issues
+}
+
+// Latest returns the newest entry for each item, newest first
+func Latest(entries []Entry) []Entry {
+	latest := []Entry{}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if seen[e.Key()] {
+			continue
+		}
+		seen[e.Key()] = true
+		latest = append(latest, e)
+	}
+	return latest
+}
+
+// Since returns the entries newer than t
+func Since(entries []Entry, t time.Time) []Entry {
+	since := []Entry{}
+	for _, e := range entries {
+		if e.Time.After(t) {
+			since = append(since, e)
+		}
+	}
+	return since
+}
@@ -0,0 +1,81 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/radarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+var now = time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC)
+
+var entries = []Entry{
+	{Service: "radarr", ID: 1, Type: "Download", Title: "Film (1970)", Time: now.Add(-1 * time.Hour)},
+	{Service: "sonarr", ID: 7, Type: "Grab", Title: "Show - 1x01 - Pilot", Time: now.Add(-2 * time.Hour)},
+	{Service: "radarr", ID: 2, Type: "Grab", Title: "Other Film (1971)", Time: now.Add(-20 * time.Hour)},
+	{Service: "radarr", ID: 1, Type: "Grab", Title: "Film (1970)", Time: now.Add(-21 * time.Hour)},
+	{Service: "sonarr", ID: 3, Type: "Download", Title: "Show - 1x00 - Special", Time: now.Add(-22 * time.Hour)},
+	{Service: "radarr", Type: "HealthRestored", Title: "Indexer down", Check: "IndexerStatusCheck", Time: now.Add(-23 * time.Hour)},
+	{Service: "radarr", Type: "Health", Title: "Indexer down", Check: "IndexerStatusCheck", Time: now.Add(-24 * time.Hour)},
+	{Service: "sonarr", Type: "Health", Title: "Disk almost full", Check: "DiskSpaceCheck", Time: now.Add(-25 * time.Hour)},
+	{Service: "sonarr", Type: "Health", Title: "Disk full", Check: "DiskSpaceCheck", Time: now.Add(-26 * time.Hour)},
+}
+
+func TestDownloads(t *testing.T) {
+	tests := map[string]struct {
+		n        int
+		expected []Entry
+	}{
+		"all":     {n: 10, expected: []Entry{entries[0], entries[4]}},
+		"limited": {n: 1, expected: []Entry{entries[0]}},
+		"none":    {n: 0, expected: []Entry{}},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, Downloads(entries, tc.n))
+	}
+}
+
+func TestStuck(t *testing.T) {
+	tests := map[string]struct {
+		threshold time.Duration
+		expected  []Entry
+	}{
+		"recent grabs": {threshold: time.Hour, expected: []Entry{entries[1], entries[2]}},
+		"old grabs":    {threshold: 12 * time.Hour, expected: []Entry{entries[2]}},
+		"nothing old":  {threshold: 48 * time.Hour, expected: []Entry{}},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, Stuck(entries, now, tc.threshold))
+	}
+}
+
+func TestHealth(t *testing.T) {
+	assert.Equal(t, []Entry{entries[7]}, Health(entries))
+}
+
+func TestNewEntry(t *testing.T) {
+	d := &radarr.Data{
+		Movie:            &radarr.Movie{ID: 1, Title: "Film", Year: 1970, Tags: []string{"kids"}},
+		Release:          &radarr.Release{Quality: "Bluray-1080p", ReleaseGroup: "legit", Size: 100},
+		CustomFormatInfo: &radarr.CustomFormatInfo{CustomFormatScore: -10},
+		EventType:        arrhook.EventGrab,
+	}
+
+	e := NewEntry(arr.Receive(d, nil, now))
+
+	assert.Equal(t, "Film (1970)", e.Title)
+	assert.Equal(t, "Bluray-1080p", e.Quality)
+	assert.Equal(t, 100, e.Size)
+	assert.Equal(t, []string{"kids"}, e.Tags)
+	assert.Equal(t, -10, *e.Score)
+	assert.Equal(t, now, e.Time)
+
+	d = &radarr.Data{Movie: d.Movie, MovieFile: &radarr.MovieFile{Quality: "WEBDL-720p"}, DeleteReason: "upgrade", EventType: arrhook.EventMovieFileDelete}
+	assert.Equal(t, "upgrade", NewEntry(arr.Receive(d, nil, now)).DeleteReason)
+}
@@ -4,136 +4,129 @@ Package radarr defines the structure of a radarr webhook
 package radarr
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
-)
-
-// ParseError defines a custom error type for failing to turn
-// a webhook into a radarr.Data struct
-type ParseError struct{}
 
-func (pe *ParseError) Error() string {
-	return "Unable to parse webhook"
-}
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
 
 // Data defines the structure of a Radarr webhook
-type Data struct {
-	DeleteReason       string               `json:"deleteReason,omitempty"`
-	DeletedFiles       bool                 `json:"deletedFiles,omitempty"`
-	DownloadClient     string               `json:"downloadClient,omitempty"`
-	DownloadClientType string               `json:"downloadClientType,omitempty"`
-	DownloadID         string               `json:"downloadId,omitempty"`
-	EventType          string               `json:"eventType,omitempty"`
-	InstanceName       string               `json:"instanceName,omitempty"`
-	IsUpgrade          bool                 `json:"isUpgrade,omitempty"`
-	Movie              Movie                `json:"movie"`
-	MovieFile          *MovieFile           `json:"movieFile,omitempty"`
-	Release            *Release             `json:"release,omitempty"`
-	RemoteMovie        *RemoteMovie         `json:"remoteMovie,omitempty"`
-	RenamedMovieFiles  []*RenamedMovieFiles `json:"renamedMovieFiles,omitempty"`
-	ApplicationURL     string               `json:"applicationUrl,omitempty"`
-}
+type Data arrhook.Radarr
 
 // Movie defines a movie
-type Movie struct {
-	ID          int    `json:"id,omitempty"`
-	Title       string `json:"title,omitempty"`
-	Year        int    `json:"year,omitempty"`
-	ReleaseDate string `json:"releaseDate,omitempty"`
-	FolderPath  string `json:"folderPath,omitempty"`
-	TMDBID      int    `json:"tmdbId,omitempty"`
-	IMDBID      string `json:"imdbId,omitempty"`
-}
+type Movie = arr.Movie
 
 // RemoteMovie defines external data about a movie
-type RemoteMovie struct {
-	TMDBID int    `json:"tmdbId,omitempty"`
-	IMDBID string `json:"imdbId,omitempty"`
-	Title  string `json:"title,omitempty"`
-	Year   int    `json:"year,omitempty"`
-}
+type RemoteMovie = arr.RemoteMovie
 
 // Release defines metadata about a movie release
-type Release struct {
-	Quality        string `json:"quality,omitempty"`
-	QualityVersion int    `json:"qualityVersion,omitempty"`
-	ReleaseGroup   string `json:"releaseGroup,omitempty"`
-	ReleaseTitle   string `json:"releaseTitle,omitempty"`
-	Indexer        string `json:"indexer,omitempty"`
-	Size           int    `json:"size,omitempty"`
-}
+type Release = arr.Release
 
 // MovieFile defines metadata about a local movie file
-type MovieFile struct {
-	ID             int    `json:"id,omitempty"`
-	RelativePath   string `json:"relativePath,omitempty"`
-	Path           string `json:"path,omitempty"`
-	Quality        string `json:"quality,omitempty"`
-	QualityVersion int    `json:"qualityVersion,omitempty"`
-	ReleaseGroup   string `json:"releaseGroup,omitempty"`
-	SceneName      string `json:"sceneName,omitempty"`
-	IndexerFlags   string `json:"indexerFlags,omitempty"`
-	Size           int    `json:"size,omitempty"`
-}
+type MovieFile = arr.File
+
+// DeletedFiles is sent as a bool on MovieDelete, and as the replaced files
+// on an upgrade Download
+type DeletedFiles = arr.DeletedFiles
 
 // RenamedMovieFiles defines metadata about a movie file rename
-type RenamedMovieFiles struct {
-	PreviousRelativePath string `json:"previousRelativePath,omitempty"`
-	PreviousPath         string `json:"previousPath,omitempty"`
-	ID                   int    `json:"id,omitempty"`
-	RelativePath         string `json:"relativePath,omitempty"`
-	Quality              string `json:"quality,omitempty"`
-	QualityVersion       int    `json:"qualityVersion,omitempty"`
-	IndexerFlags         string `json:"indexerFlags,omitempty"`
-	Size                 int    `json:"size,omitempty"`
-}
+type RenamedMovieFiles = arr.RenamedFile
 
-// OnHealthIssue defines information about a Radarr health issue
-type OnHealthIssue struct {
-	Level     string `json:"level,omitempty"`
-	Message   string `json:"message,omitempty"`
-	Type      string `json:"type,omitempty"`
-	WikiURL   string `json:"wikiUrl,omitempty"`
-	EventType string `json:"eventType,omitempty"`
-}
+// CustomFormatInfo defines the custom formats a release matched
+type CustomFormatInfo = arr.CustomFormatInfo
+
+// CustomFormat defines a custom format set up in Radarr
+type CustomFormat = arr.CustomFormat
+
+// DownloadInfo defines the download client's view of a release
+type DownloadInfo = arr.DownloadInfo
+
+// StatusMessage defines why a download can't be imported
+type StatusMessage = arr.StatusMessage
 
 // ParseWebhook takes a webhook and turns it into a struct
 func ParseWebhook(body []byte) (*Data, error) {
-	d := Data{}
-
-	err := json.Unmarshal(body, &d)
+	r, err := arrhook.ParseRadarr(body)
 	if err != nil {
-		slog.With("package", "radarr").Error("Invalid JSON")
-		return nil, &ParseError{}
-	}
-
-	if d.Movie.ID == 0 {
 		slog.With("package", "radarr").Error("Bad Webhook")
-		return nil, &ParseError{}
+		return nil, err
 	}
 
-	return &d, nil
+	return (*Data)(r), nil
 }
 
-func (d *Data) ID() int             { return d.Movie.ID }
-func (d *Data) IMDBID() string      { return d.Movie.IMDBID }
-func (d *Data) ReleaseDate() string { return d.Movie.ReleaseDate }
-func (d *Data) Service() string     { return "radarr" }
-func (d *Data) Title() string       { return fmt.Sprintf("%s (%d)", d.Movie.Title, d.Movie.Year) }
-func (d *Data) Type() string        { return d.EventType }
-func (d *Data) URL() string         { return fmt.Sprintf("%s/movie/%d", d.ApplicationURL, d.Movie.TMDBID) }
-
-func (d *Data) Quality() string {
-	if d.EventType == "Grab" {
-		return d.Release.Quality
+// movie returns the movie the webhook is about, which is empty for events
+// that aren't about one
+func (d *Data) movie() Movie {
+	if d.Movie == nil {
+		return Movie{}
 	}
-	return d.MovieFile.Quality
+	return *d.Movie
 }
 
-func (d *Data) ReleaseGroup() string {
-	if d.EventType == "Grab" {
-		return d.Release.ReleaseGroup
+func (d *Data) Kind() arrhook.EventKind { return d.EventType }
+func (d *Data) Instance() string        { return d.InstanceName }
+func (d *Data) Service() string         { return "radarr" }
+func (d *Data) URL() string             { return fmt.Sprintf("%s/movie/%d", d.ApplicationURL, d.movie().TMDBID) }
+
+func (d *Data) Title() string {
+	switch d.EventType {
+	case arrhook.EventHealth, arrhook.EventHealthRestored:
+		return d.Message
+	case arrhook.EventApplicationUpdate:
+		return fmt.Sprintf("%s → %s", d.PreviousVersion, d.NewVersion)
 	}
-	return d.MovieFile.ReleaseGroup
+	m := d.movie()
+	if m.ID == 0 && d.DownloadInfo != nil {
+		return d.DownloadInfo.Title
+	}
+	return fmt.Sprintf("%s (%d)", m.Title, m.Year)
+}
+
+// Event normalizes the webhook into an arr.Event
+func (d *Data) Event() *arr.Event {
+	m := d.movie()
+	e := arr.Event{
+		Source:      d.Service(),
+		Instance:    d.InstanceName,
+		Kind:        d.EventType,
+		Name:        d.EventName,
+		ID:          m.ID,
+		Title:       d.Title(),
+		URL:         d.URL(),
+		DeepURL:     d.URL(),
+		ReleaseDate: m.ReleaseDate,
+		Upgrade:     d.IsUpgrade,
+		Subject: arr.Subject{
+			ID:     m.ID,
+			Title:  m.Title,
+			Year:   m.Year,
+			IMDBID: m.IMDBID,
+			TMDBID: m.TMDBID,
+			URL:    d.URL(),
+			Path:   m.FolderPath,
+			Tags:   m.Tags,
+		},
+		Release:            d.Release,
+		DownloadID:         d.DownloadID,
+		DownloadClient:     d.DownloadClient,
+		DownloadClientType: d.DownloadClientType,
+		CustomFormats:      d.CustomFormatInfo,
+		Replaced:           d.DeletedFiles.Files,
+		DeleteReason:       d.DeleteReason,
+		Renames:            d.RenamedMovieFiles,
+		Health:             (*arrhook.Radarr)(d).Health(),
+		Update:             (*arrhook.Radarr)(d).Update(),
+	}
+
+	if d.EventType == arrhook.EventManualInteractionRequired {
+		e.Interaction = arr.NewInteraction(d.ApplicationURL, d.DownloadInfo, d.DownloadStatus, d.StatusMessages)
+	}
+
+	if d.MovieFile != nil {
+		e.Files = []arr.File{*d.MovieFile}
+	}
+
+	return &e
 }
@@ -4,6 +4,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
 )
 
 var grabJSON = []byte(`{
@@ -70,7 +73,7 @@ var downloadJSON = []byte(`{
 	}`)
 
 var grabRadarr = &Data{
-	Movie: Movie{
+	Movie: &Movie{
 		ID:          686,
 		Title:       "Film",
 		Year:        1970,
@@ -95,11 +98,12 @@ var grabRadarr = &Data{
 	},
 	DownloadClient:     "sab",
 	DownloadClientType: "usenet",
-	EventType:          "Grab",
+	EventType:          arrhook.EventGrab,
+	EventName:          "Grab",
 }
 
 var downloadRadarr = &Data{
-	Movie: Movie{
+	Movie: &Movie{
 		ID:          686,
 		Title:       "Film",
 		Year:        1970,
@@ -129,7 +133,68 @@ var downloadRadarr = &Data{
 	DownloadClient:     "sab",
 	DownloadClientType: "usenet",
 	DownloadID:         "sab_Film.1970.1080p.BluRay.x265-legit_1234",
-	EventType:          "Download",
+	EventType:          arrhook.EventDownload,
+	EventName:          "Download",
+}
+
+var renameJSON = []byte(`{
+	"movie": {
+		"id": 686,
+		"title": "Film",
+		"year": 1970,
+		"tmdbId": 123
+	},
+	"renamedMovieFiles": [{
+		"previousRelativePath": "film.mkv",
+		"previousPath": "/path/to/film.mkv",
+		"id": 36745,
+		"relativePath": "Film (1970).mkv",
+		"quality": "WEBDL-1080p",
+		"size": 1234578
+	}],
+	"eventType": "Rename"
+	}`)
+
+var renameRadarr = &Data{
+	Movie: &Movie{
+		ID:     686,
+		Title:  "Film",
+		Year:   1970,
+		TMDBID: 123,
+	},
+	RenamedMovieFiles: []RenamedMovieFiles{
+		{
+			PreviousRelativePath: "film.mkv",
+			PreviousPath:         "/path/to/film.mkv",
+			ID:                   36745,
+			RelativePath:         "Film (1970).mkv",
+			Quality:              "WEBDL-1080p",
+			Size:                 1234578,
+		},
+	},
+	EventType: arrhook.EventRename,
+	EventName: "Rename",
+}
+
+var healthJSON = []byte(`{
+	"level": "warning",
+	"message": "Indexers unavailable due to failures: usenet",
+	"type": "IndexerStatusCheck",
+	"wikiUrl": "https://wiki.servarr.com/radarr/system#indexers-are-unavailable-due-to-failures",
+	"eventType": "Health",
+	"instanceName": "Radarr",
+	"applicationUrl": "http://localhost"
+	}`)
+
+var healthRadarr = &Data{
+	EventType:      arrhook.EventHealth,
+	EventName:      "Health",
+	InstanceName:   "Radarr",
+	ApplicationURL: "http://localhost",
+	Level:          "warning",
+	Message:        "Indexers unavailable due to failures: usenet",
+	HealthType:     "IndexerStatusCheck",
+	WikiURL:        "https://wiki.servarr.com/radarr/system#indexers-are-unavailable-due-to-failures",
 }
 
 func TestParseWebhook(t *testing.T) {
@@ -140,8 +205,36 @@ func TestParseWebhook(t *testing.T) {
 	}{
 		"grabbed":    {input: grabJSON, expectedData: grabRadarr, expectedErr: nil},
 		"downloaded": {input: downloadJSON, expectedData: downloadRadarr, expectedErr: nil},
-		"malformed":  {input: []byte("}"), expectedData: nil, expectedErr: &ParseError{}},
-		"invalid":    {input: []byte("{}"), expectedData: nil, expectedErr: &ParseError{}},
+		"renamed":    {input: renameJSON, expectedData: renameRadarr, expectedErr: nil},
+		"health":     {input: healthJSON, expectedData: healthRadarr, expectedErr: nil},
+		"file deleted": {
+			input: []byte(`{"movie": {"id": 686, "title": "Film", "year": 1970, "tmdbId": 123}, "movieFile": {"id": 36745, "relativePath": "Film (1970).mkv", "quality": "WEBDL-1080p", "size": 1234578}, "deleteReason": "manual", "eventType": "MovieFileDelete"}`),
+			expectedData: &Data{
+				Movie:        &Movie{ID: 686, Title: "Film", Year: 1970, TMDBID: 123},
+				MovieFile:    &MovieFile{ID: 36745, RelativePath: "Film (1970).mkv", Quality: "WEBDL-1080p", Size: 1234578},
+				DeleteReason: "manual",
+				EventType:    arrhook.EventMovieFileDelete,
+				EventName:    "MovieFileDelete",
+			},
+			expectedErr: nil,
+		},
+		"update": {
+			input:        []byte(`{"previousVersion": "5.1.3.8246", "newVersion": "5.2.0.8270", "message": "Radarr updated", "eventType": "ApplicationUpdate"}`),
+			expectedData: &Data{EventType: arrhook.EventApplicationUpdate, EventName: "ApplicationUpdate", PreviousVersion: "5.1.3.8246", NewVersion: "5.2.0.8270", Message: "Radarr updated"},
+			expectedErr:  nil,
+		},
+		"test": {
+			input:        []byte(`{"eventType": "Test", "instanceName": "Radarr"}`),
+			expectedData: &Data{EventType: arrhook.EventTest, EventName: "Test", InstanceName: "Radarr"},
+			expectedErr:  nil,
+		},
+		"no check":  {input: []byte(`{"eventType": "Health"}`), expectedData: nil, expectedErr: &arr.ParseError{}},
+		"malformed": {input: []byte("}"), expectedData: nil, expectedErr: &arr.ParseError{}},
+		"invalid":   {input: []byte(`{"eventType": "Grab"}`), expectedData: nil, expectedErr: &arr.ParseError{}},
+		"empty":     {input: []byte(`{}`), expectedData: nil, expectedErr: &arr.ParseError{}},
+		"null":      {input: []byte(`null`), expectedData: nil, expectedErr: &arr.ParseError{}},
+		"no type":   {input: []byte(`{"movie": {"id": 686}}`), expectedData: nil, expectedErr: &arr.ParseError{}},
+		"unknown":   {input: []byte(`{"eventType": "MovieRetitled"}`), expectedData: &Data{EventType: arrhook.EventUnknown, EventName: "MovieRetitled"}, expectedErr: nil},
 	}
 
 	for _, tc := range tests {
@@ -155,3 +248,134 @@ func TestParseWebhook(t *testing.T) {
 
 	}
 }
+
+func TestMovieFileDelete(t *testing.T) {
+	d, err := ParseWebhook([]byte(`{
+	"movie": {"id": 686, "title": "Film", "year": 1970},
+	"movieFile": {"id": 36745, "relativePath": "Film (1970).mkv", "quality": "Bluray-1080p", "size": 100},
+	"deleteReason": "upgrade",
+	"eventType": "MovieFileDelete"
+	}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "upgrade", d.Event().DeleteReason)
+	assert.Equal(t, "Film (1970)", d.Title())
+	assert.Equal(t, "Bluray-1080p", d.Event().Quality())
+	assert.Equal(t, 100, d.Event().Size())
+}
+
+func TestRenames(t *testing.T) {
+	assert.Equal(t, renameRadarr.RenamedMovieFiles, renameRadarr.Event().Renames)
+	assert.Empty(t, grabRadarr.Event().Renames)
+}
+
+func TestHealthCheck(t *testing.T) {
+	assert.Equal(t, &arr.Health{
+		Level:     "warning",
+		Message:   "Indexers unavailable due to failures: usenet",
+		Type:      "IndexerStatusCheck",
+		WikiURL:   "https://wiki.servarr.com/radarr/system#indexers-are-unavailable-due-to-failures",
+		EventType: "Health",
+	}, healthRadarr.Event().Health)
+	assert.Equal(t, "Indexers unavailable due to failures: usenet", healthRadarr.Title())
+	assert.Nil(t, grabRadarr.Event().Health)
+}
+
+func TestDeletedFiles(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		expected DeletedFiles
+	}{
+		"movie delete": {input: []byte(`true`), expected: DeletedFiles{Deleted: true}},
+		"upgrade": {
+			input:    []byte(`[{"id": 1, "quality": "WEBDL-720p", "releaseGroup": "old", "size": 100}]`),
+			expected: DeletedFiles{Files: []MovieFile{{ID: 1, Quality: "WEBDL-720p", ReleaseGroup: "old", Size: 100}}},
+		},
+	}
+
+	for name, tc := range tests {
+		actual := DeletedFiles{}
+		assert.NoError(t, actual.UnmarshalJSON(tc.input), name)
+		assert.Equal(t, tc.expected, actual, name)
+
+		b, err := actual.MarshalJSON()
+		assert.NoError(t, err, name)
+		assert.JSONEq(t, string(tc.input), string(b), name)
+	}
+}
+
+func TestInteraction(t *testing.T) {
+	input := []byte(`{
+	"downloadInfo": {"quality": "Bluray-1080p", "title": "Film.1970.1080p.BluRay-GroupX", "size": 100},
+	"downloadClient": "qBittorrent",
+	"downloadId": "ABC123",
+	"downloadStatus": "Warning",
+	"downloadStatusMessages": [{"title": "Film.1970.1080p.BluRay-GroupX", "messages": ["Unknown Movie"]}],
+	"eventType": "ManualInteractionRequired",
+	"applicationUrl": "http://localhost"
+	}`)
+
+	d, err := ParseWebhook(input)
+	assert.NoError(t, err)
+	assert.Equal(t, "Film.1970.1080p.BluRay-GroupX", d.Title())
+	assert.Equal(t, "ABC123", d.DownloadID)
+	assert.Equal(t, "qBittorrent", d.Event().DownloadClient)
+	assert.Equal(t, &arr.Interaction{
+		ReleaseTitle: "Film.1970.1080p.BluRay-GroupX",
+		Status:       "Warning",
+		Messages:     []string{"Unknown Movie"},
+		QueueURL:     "http://localhost/activity/queue",
+	}, d.Event().Interaction)
+	assert.Nil(t, grabRadarr.Event().Interaction)
+}
+
+func TestCustomFormats(t *testing.T) {
+	d, err := ParseWebhook([]byte(`{
+	"movie": {"id": 686, "title": "Film", "year": 1970},
+	"customFormatInfo": {"customFormats": [{"id": 1, "name": "HDR"}, {"id": 4, "name": "x265"}], "customFormatScore": 25},
+	"eventType": "Grab"
+	}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &CustomFormatInfo{CustomFormats: []CustomFormat{{ID: 1, Name: "HDR"}, {ID: 4, Name: "x265"}}, CustomFormatScore: 25}, d.Event().CustomFormats)
+	assert.Equal(t, 25, *d.Event().Score())
+	assert.Nil(t, grabRadarr.Event().CustomFormats)
+}
+
+func TestTags(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		expected []string
+	}{
+		"labels":  {input: []byte(`{"movie": {"id": 1, "tags": ["kids", "4k"]}, "eventType": "Grab"}`), expected: []string{"kids", "4k"}},
+		"ids":     {input: []byte(`{"movie": {"id": 1, "tags": [1, 12]}, "eventType": "Grab"}`), expected: []string{"1", "12"}},
+		"no tags": {input: []byte(`{"movie": {"id": 1}, "eventType": "Grab"}`), expected: nil},
+	}
+
+	for name, tc := range tests {
+		d, err := ParseWebhook(tc.input)
+		assert.NoError(t, err, name)
+		assert.Equal(t, tc.expected, d.Event().Subject.Tags, name)
+	}
+
+	_, err := ParseWebhook([]byte(`{"movie": {"id": 1, "tags": [{}]}, "eventType": "Grab"}`))
+	assert.Error(t, err)
+}
+
+func TestEvent(t *testing.T) {
+	e := downloadRadarr.Event()
+
+	assert.Equal(t, "radarr", e.Source)
+	assert.Equal(t, arrhook.EventDownload, e.Kind)
+	assert.Equal(t, "Download", e.Type())
+	assert.Equal(t, 686, e.ID)
+	assert.Equal(t, "Film (1970)", e.Title)
+	assert.Equal(t, e.URL, e.DeepURL)
+	assert.Equal(t, arr.Subject{ID: 686, Title: "Film", Year: 1970, IMDBID: "tt456", TMDBID: 123, URL: "/movie/123", Path: "/path/to/"}, e.Subject)
+	assert.Nil(t, e.Release)
+	assert.Equal(t, []arr.File{*downloadRadarr.MovieFile}, e.Files)
+
+	e = grabRadarr.Event()
+	assert.Equal(t, grabRadarr.Release, e.Release)
+	assert.Empty(t, e.Files)
+}
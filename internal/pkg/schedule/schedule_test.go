@@ -0,0 +1,57 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Thursday
+var start = time.Date(1970, 1, 1, 10, 30, 15, 0, time.UTC)
+
+func TestNext(t *testing.T) {
+	tests := map[string]struct {
+		spec     string
+		expected time.Time
+	}{
+		"every minute":   {spec: "* * * * *", expected: time.Date(1970, 1, 1, 10, 31, 0, 0, time.UTC)},
+		"daily later":    {spec: "0 18 * * *", expected: time.Date(1970, 1, 1, 18, 0, 0, 0, time.UTC)},
+		"daily tomorrow": {spec: "0 9 * * *", expected: time.Date(1970, 1, 2, 9, 0, 0, 0, time.UTC)},
+		"weekly monday":  {spec: "0 9 * * 1", expected: time.Date(1970, 1, 5, 9, 0, 0, 0, time.UTC)},
+		"sunday as 7":    {spec: "0 9 * * 7", expected: time.Date(1970, 1, 4, 9, 0, 0, 0, time.UTC)},
+		"weekdays":       {spec: "0 8 * * 1-5", expected: time.Date(1970, 1, 2, 8, 0, 0, 0, time.UTC)},
+		"steps":          {spec: "*/20 * * * *", expected: time.Date(1970, 1, 1, 10, 40, 0, 0, time.UTC)},
+		"list":           {spec: "15,45 * * * *", expected: time.Date(1970, 1, 1, 10, 45, 0, 0, time.UTC)},
+		"monthly":        {spec: "0 0 1 * *", expected: time.Date(1970, 2, 1, 0, 0, 0, 0, time.UTC)},
+		"day or weekday": {spec: "0 0 15 * 6", expected: time.Date(1970, 1, 3, 0, 0, 0, 0, time.UTC)},
+		"never":          {spec: "0 0 31 2 *", expected: time.Time{}},
+	}
+
+	for name, tc := range tests {
+		s, err := Parse(tc.spec)
+		assert.NoError(t, err, name)
+		assert.Equal(t, tc.expected, s.Next(start), name)
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	s, err := Parse("0 9 * * *")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC), s.Previous(start))
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"too few fields": "* * * *",
+		"out of range":   "60 * * * *",
+		"bad range":      "5-1 * * * *",
+		"bad step":       "*/0 * * * *",
+		"not a number":   "a * * * *",
+	}
+
+	for name, spec := range tests {
+		_, err := Parse(spec)
+		assert.Error(t, err, name)
+	}
+}
@@ -8,18 +8,22 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"time"
 
-	"github.com/mbarrin/gwarr/internal/pkg/data"
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
 	"github.com/mbarrin/gwarr/internal/pkg/radarr"
 	"github.com/mbarrin/gwarr/internal/pkg/slack"
 	"github.com/mbarrin/gwarr/internal/pkg/sonarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var sc slack.Client
 
-// Start starts a server to receive webhooks
-func Start(port int64, client slack.Client, radarr, sonarr bool) error {
+// Start starts a server to receive webhooks. When home is set the
+// Slack Events API and interactivity endpoints are served as well
+func Start(port int64, client slack.Client, radarr, sonarr, home bool) error {
 	sc = client
 
 	if radarr {
@@ -30,6 +34,11 @@ func Start(port int64, client slack.Client, radarr, sonarr bool) error {
 		http.HandleFunc("/sonarr", webhook)
 	}
 
+	if home {
+		http.HandleFunc("/slack/events", slackEvents)
+		http.HandleFunc("/slack/interactions", slackInteractions)
+	}
+
 	http.Handle("/metrics", promhttp.Handler())
 
 	p := fmt.Sprintf(":%d", port)
@@ -57,13 +66,13 @@ func webhook(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	var data data.Data
+	var src arr.Source
 	var err error
 
 	if r.URL.Path == "/sonarr" {
-		data, err = sonarr.ParseWebhook(body)
+		src, err = sonarr.ParseWebhook(body)
 	} else if r.URL.Path == "/radarr" {
-		data, err = radarr.ParseWebhook(body)
+		src, err = radarr.ParseWebhook(body)
 	}
 
 	slog.With("package", "server").Debug(string(body))
@@ -73,10 +82,86 @@ func webhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sc.Post(data)
+	e := arr.Receive(src, body, time.Now())
+
+	// A newer *arr can send events gwarr doesn't know yet, which are
+	// posted as they were sent
+	if e.Kind == arrhook.EventUnknown {
+		slog.With("package", "server").Warn("Unknown event", "service", e.Source, "instance", e.Instance, "eventType", e.Name)
+	}
+
+	err = sc.Post(e)
 	if err != nil {
 		slog.With("package", "server").Error(err.Error())
 		http.Error(w, err.Error(), 500)
 		return
 	}
 }
+
+func slackEvents(w http.ResponseWriter, r *http.Request) {
+	body, ok := slackBody(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := sc.HandleEvent(body)
+	if err != nil {
+		slog.With("package", "server").Error(err.Error())
+		http.Error(w, "Invalid Content", 400)
+		return
+	}
+
+	if resp != nil {
+		w.Header().Set("Content-Type", "text/plain")
+		_, err = w.Write(resp)
+		if err != nil {
+			slog.With("package", "server").Error(err.Error())
+		}
+	}
+}
+
+func slackInteractions(w http.ResponseWriter, r *http.Request) {
+	body, ok := slackBody(w, r)
+	if !ok {
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		slog.With("package", "server").Error(err.Error())
+		http.Error(w, "Invalid Content", 400)
+		return
+	}
+
+	err = sc.HandleInteraction(form.Get("payload"))
+	if err != nil {
+		slog.With("package", "server").Error(err.Error())
+		http.Error(w, err.Error(), 500)
+		return
+	}
+}
+
+// slackBody reads a request sent by Slack and checks its signature
+func slackBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if r.Method != "POST" {
+		http.Error(w, "Invalid Method", 405)
+		return nil, false
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	defer func() {
+		err := r.Body.Close()
+		if err != nil {
+			slog.With("package", "server").Error("Failed to close body")
+		}
+	}()
+
+	err := sc.Verify(r.Header, body)
+	if err != nil {
+		slog.With("package", "server").Error(err.Error())
+		http.Error(w, "Unauthorized", 401)
+		return nil, false
+	}
+
+	return body, true
+}
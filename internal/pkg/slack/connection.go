@@ -0,0 +1,49 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+)
+
+// postTest answers an *arr test notification by posting to every route,
+// whatever it is configured to receive, so the whole path can be checked.
+// The error names each route that failed so the *arr can show why
+func (sc *Client) postTest(e *arr.Event) error {
+	var errs []error
+	for _, r := range sc.routes {
+		response, err := sc.send(r, "chat.postMessage", onTestInfo(r, e))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("route %s: %w", r.Name, err))
+			continue
+		}
+
+		if !response.OK {
+			errs = append(errs, fmt.Errorf("route %s: %s", r.Name, response.Error))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func onTestInfo(r config.Route, e *arr.Event) body {
+	name := service(e)
+	if e.Instance != "" {
+		name = e.Instance
+	}
+
+	summary := fmt.Sprintf("Connection from %s works", name)
+	return body{
+		Channel: r.Channel,
+		Text:    summary,
+		Blocks: []block{
+			{
+				Type: "section",
+				Text: &text{Type: "mrkdwn", Text: ":white_check_mark: " + summary},
+			},
+			plain(fmt.Sprintf("Test notification delivered to route `%s`", r.Name)),
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package slack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/internal/pkg/radarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+var radarrOnTest = radarr.Data{
+	EventType:    arrhook.EventTest,
+	InstanceName: "Radarr 4K",
+}
+
+func TestOnTestInfo(t *testing.T) {
+	expected := body{
+		Channel: "c123",
+		Text:    "Connection from Radarr 4K works",
+		Blocks: []block{
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: ":white_check_mark: Connection from Radarr 4K works"}},
+			plain("Test notification delivered to route `movies`"),
+		},
+	}
+
+	assert.Equal(t, expected, onTestInfo(config.Route{Name: "movies", Channel: "c123"}, radarrOnTest.Event()))
+
+	unnamed := radarr.Data{EventType: arrhook.EventTest}
+	assert.Equal(t, "Connection from Radarr works", onTestInfo(config.Route{}, unnamed.Event()).Text)
+}
+
+func TestPostTest(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ok.Close()
+
+	gone := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("no_service"))
+	}))
+	defer gone.Close()
+
+	sc := Client{
+		client: http.Client{},
+		routes: []config.Route{
+			{Name: "movies", Webhook: ok.URL, Services: []string{"sonarr"}},
+			{Name: "old", Webhook: gone.URL, DigestOnly: true},
+		},
+	}
+
+	assert.EqualError(t, sc.postTest(radarrOnTest.Event()), "route old: no_service")
+
+	sc.routes = sc.routes[:1]
+	assert.NoError(t, sc.postTest(radarrOnTest.Event()))
+}
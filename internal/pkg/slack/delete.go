@@ -0,0 +1,75 @@
+package slack
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+// postDelete handles an item being removed according to the route's
+// onDelete policy. The record of the original message is kept after a
+// download, but when it can't be found a new message is posted instead
+func (sc *Client) postDelete(r config.Route, e *arr.Event) error {
+	ts := sc.load(r, e).TS
+
+	response, err := sc.call(deleteMessage(r, e, ts))
+	if err != nil {
+		return err
+	}
+
+	// The message may have been removed by hand since it was cached
+	if !response.OK && response.Error == "message_not_found" && ts != "" {
+		slog.With("package", "slack").Debug("Original message not found, posting instead")
+		response, err = sc.call(deleteMessage(r, e, ""))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !response.OK {
+		slog.Error(response.Error)
+		return nil
+	}
+
+	sc.forget(r, e)
+	if r.ThreadSeries && e.Kind == arrhook.EventSeriesDelete {
+		sc.forgetParent(r, e)
+	}
+
+	return nil
+}
+
+// deleteMessage returns the Slack method and message for a deletion
+func deleteMessage(r config.Route, e *arr.Event, ts string) (string, body) {
+	if ts == "" {
+		if r.OnDelete == "strike" || r.OnDelete == "delete" {
+			return "chat.postMessage", onRemovedInfo(r.Channel, e, "")
+		}
+		return "chat.postMessage", onDeleteInfo(r.Channel, e)
+	}
+
+	switch r.OnDelete {
+	case "strike":
+		return "chat.update", onRemovedInfo(r.Channel, e, ts)
+	case "delete":
+		return "chat.delete", body{Channel: r.Channel, TS: ts}
+	case "thread":
+		b := onDeleteInfo(r.Channel, e)
+		b.ThreadTS = ts
+		return "chat.postMessage", b
+	default:
+		return "chat.postMessage", onDeleteInfo(r.Channel, e)
+	}
+}
+
+func onRemovedInfo(c string, e *arr.Event, ts string) body {
+	b := base(c, e)
+	b.TS = ts
+	b.Text = fmt.Sprintf("Removed: %s", e.Title)
+	b.Blocks[0].Text.Text = fmt.Sprintf(":wastebasket: Removed: %s", e.Title)
+	b.Blocks[1].Text.Text = fmt.Sprintf("~%s~", e.URL)
+	return b
+}
@@ -0,0 +1,129 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/internal/pkg/radarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+var radarrOnDelete = radarr.Data{
+	Movie: &radarr.Movie{
+		Title:       "Film",
+		Year:        1970,
+		ReleaseDate: "1970-01-01",
+		IMDBID:      "tt8415836",
+		TMDBID:      55,
+	},
+	EventType:      arrhook.EventMovieDelete,
+	ApplicationURL: "http://localhost",
+}
+
+func TestDeleteMessage(t *testing.T) {
+	removed := onRemovedInfo("c123", radarrOnDelete.Event(), "1234")
+	threaded := onDeleteInfo("c123", radarrOnDelete.Event())
+	threaded.ThreadTS = "1234"
+
+	tests := map[string]struct {
+		policy         string
+		ts             string
+		expectedMethod string
+		expectedBody   body
+	}{
+		"post":            {policy: "", ts: "1234", expectedMethod: "chat.postMessage", expectedBody: onDeleteInfo("c123", radarrOnDelete.Event())},
+		"strike":          {policy: "strike", ts: "1234", expectedMethod: "chat.update", expectedBody: removed},
+		"delete":          {policy: "delete", ts: "1234", expectedMethod: "chat.delete", expectedBody: body{Channel: "c123", TS: "1234"}},
+		"thread":          {policy: "thread", ts: "1234", expectedMethod: "chat.postMessage", expectedBody: threaded},
+		"strike uncached": {policy: "strike", ts: "", expectedMethod: "chat.postMessage", expectedBody: onRemovedInfo("c123", radarrOnDelete.Event(), "")},
+		"delete uncached": {policy: "delete", ts: "", expectedMethod: "chat.postMessage", expectedBody: onRemovedInfo("c123", radarrOnDelete.Event(), "")},
+		"thread uncached": {policy: "thread", ts: "", expectedMethod: "chat.postMessage", expectedBody: onDeleteInfo("c123", radarrOnDelete.Event())},
+	}
+
+	for name, tc := range tests {
+		method, b := deleteMessage(config.Route{Channel: "c123", OnDelete: tc.policy}, radarrOnDelete.Event(), tc.ts)
+		assert.Equal(t, tc.expectedMethod, method, name)
+		assert.Equal(t, tc.expectedBody, b, name)
+	}
+}
+
+func TestOnRemovedInfo(t *testing.T) {
+	b := onRemovedInfo("c123", radarrOnDelete.Event(), "1234")
+	assert.Equal(t, "1234", b.TS)
+	assert.Equal(t, ":wastebasket: Removed: Film (1970)", b.Blocks[0].Text.Text)
+	assert.Equal(t, "~http://localhost/movie/55~", b.Blocks[1].Text.Text)
+}
+
+func TestPostDelete(t *testing.T) {
+	notFound := func(method string, b body) string {
+		if b.TS != "" || b.ThreadTS != "" {
+			return `{"ok": false, "error": "message_not_found"}`
+		}
+		return `{"ok": true, "ts": "5678"}`
+	}
+
+	tests := map[string]struct {
+		policy   string
+		cached   bool
+		reply    func(string, body) string
+		expected []slackCall
+	}{
+		"strike": {
+			policy: "strike", cached: true,
+			expected: []slackCall{{"chat.update", onRemovedInfo("c123", radarrOnDelete.Event(), "1234")}},
+		},
+		"delete": {
+			policy: "delete", cached: true,
+			expected: []slackCall{{"chat.delete", body{Channel: "c123", TS: "1234"}}},
+		},
+		"thread": {
+			policy: "thread", cached: true,
+			expected: []slackCall{{"chat.postMessage", func() body {
+				b := onDeleteInfo("c123", radarrOnDelete.Event())
+				b.ThreadTS = "1234"
+				return b
+			}()}},
+		},
+		"uncached": {
+			policy:   "strike",
+			expected: []slackCall{{"chat.postMessage", onRemovedInfo("c123", radarrOnDelete.Event(), "")}},
+		},
+		"message not found": {
+			policy: "strike", cached: true, reply: notFound,
+			expected: []slackCall{
+				{"chat.update", onRemovedInfo("c123", radarrOnDelete.Event(), "1234")},
+				{"chat.postMessage", onRemovedInfo("c123", radarrOnDelete.Event(), "")},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		r := config.Route{Name: "movies", Channel: "c123", OnDelete: tc.policy}
+		sc, stub := newStubClient(t, []config.Route{r})
+		stub.reply = tc.reply
+
+		e := radarrOnDelete.Event()
+		if tc.cached {
+			sc.save(r, e, &record{TS: "1234"})
+		}
+
+		assert.NoError(t, sc.postDelete(r, e), name)
+		assert.Equal(t, tc.expected, stub.Calls(), name)
+		assert.Equal(t, "", sc.load(r, e).TS, "%s: the record is forgotten", name)
+	}
+}
+
+func TestPostDeleteFailed(t *testing.T) {
+	r := config.Route{Name: "movies", Channel: "c123", OnDelete: "strike"}
+	sc, stub := newStubClient(t, []config.Route{r})
+	stub.reply = func(string, body) string { return `{"ok": false, "error": "channel_not_found"}` }
+
+	e := radarrOnDelete.Event()
+	sc.save(r, e, &record{TS: "1234"})
+
+	assert.NoError(t, sc.postDelete(r, e))
+	assert.Len(t, stub.Calls(), 1, "only a missing message is reposted")
+	assert.Equal(t, "1234", sc.load(r, e).TS, "the record is kept when Slack fails")
+}
@@ -0,0 +1,222 @@
+package slack
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/internal/pkg/history"
+	"github.com/mbarrin/gwarr/internal/pkg/schedule"
+)
+
+const (
+	// defaultStuckAfter is used when a digest does not set stuckAfter
+	defaultStuckAfter = 24 * time.Hour
+	// digestItems is the most titles listed in a single digest section
+	digestItems = 20
+)
+
+// StartDigests schedules a digest for every route that has one
+func (sc *Client) StartDigests() {
+	for _, r := range sc.routes {
+		if r.Digest == nil {
+			continue
+		}
+
+		s, err := schedule.Parse(r.Digest.Schedule)
+		if err != nil {
+			slog.With("package", "slack").Error(err.Error())
+			continue
+		}
+
+		go sc.runDigest(r, s)
+	}
+}
+
+func (sc *Client) runDigest(r config.Route, s *schedule.Schedule) {
+	for {
+		next := s.Next(time.Now())
+		if next.IsZero() {
+			slog.With("package", "slack").Error("Digest for route " + r.Name + " will never run")
+			return
+		}
+
+		time.Sleep(time.Until(next))
+
+		err := sc.PostDigest(r, s, next)
+		if err != nil {
+			slog.With("package", "slack").Error(err.Error())
+		}
+	}
+}
+
+// PostDigest posts a summary of everything since the last digest for a route
+func (sc *Client) PostDigest(r config.Route, s *schedule.Schedule, now time.Time) error {
+	key := "digest:" + r.Name
+
+	since, err := time.Parse(time.RFC3339, sc.redis.Get(ctx, key).Val())
+	if err != nil {
+		since = s.Previous(now.Add(-time.Minute))
+	}
+
+	entries, err := sc.history.Entries()
+	if err != nil {
+		return err
+	}
+
+	b := digest(r, entries, since, now)
+	if from, ok := truncated(entries, sc.history.Size(), since); ok {
+		b.Blocks = append(b.Blocks, plain(fmt.Sprintf(":warning: Only the latest %d events are kept, so this digest only covers %s onwards", sc.history.Size(), from.Format("Jan 2 15:04"))))
+	}
+
+	response, err := sc.send(r, "chat.postMessage", b)
+	if err != nil {
+		return err
+	}
+
+	if !response.OK {
+		return fmt.Errorf("digest for route %s failed: %s", r.Name, response.Error)
+	}
+
+	return sc.redis.Set(ctx, key, now.Format(time.RFC3339), 0).Err()
+}
+
+func digest(r config.Route, entries []history.Entry, since time.Time, now time.Time) body {
+	routed := []history.Entry{}
+	for _, e := range entries {
+		if r.Matches(e.Service, e.RouteType()) && r.MatchesTags(e.Tags) && r.MatchesScore(e.Score) {
+			routed = append(routed, e)
+		}
+	}
+
+	var movies, episodes, upgrades, deletions, size int
+	window := history.Since(routed, since)
+	for _, e := range window {
+		switch {
+		case e.Type == "Download" && e.Upgrade:
+			upgrades++
+			size += e.Size
+		case e.Type == "Download" && e.Service == "radarr":
+			movies++
+			size += e.Size
+		case e.Type == "Download" && e.Service == "sonarr":
+			episodes++
+			size += e.Size
+		case deletion(e):
+			deletions++
+		}
+	}
+
+	b := body{
+		Channel: r.Channel,
+		Blocks: []block{
+			header(fmt.Sprintf(":newspaper: Digest: %s to %s", since.Format("Jan 2 15:04"), now.Format("Jan 2 15:04"))),
+			{
+				Type: "section",
+				Fields: &[]text{
+					{Type: "mrkdwn", Text: fmt.Sprintf("*Movies:*\n%d", movies)},
+					{Type: "mrkdwn", Text: fmt.Sprintf("*Episodes:*\n%d", episodes)},
+					{Type: "mrkdwn", Text: fmt.Sprintf("*Upgrades:*\n%d", upgrades)},
+					{Type: "mrkdwn", Text: fmt.Sprintf("*Deletions:*\n%d", deletions)},
+					{Type: "mrkdwn", Text: "*Size Added:*\n" + humanSize(size)},
+				},
+			},
+		},
+	}
+
+	b.Blocks = append(b.Blocks, groups(r.Digest.GroupBy, window)...)
+
+	stuckAfter := r.Digest.StuckAfter.Duration
+	if stuckAfter == 0 {
+		stuckAfter = defaultStuckAfter
+	}
+
+	stuck := history.Stuck(routed, now, stuckAfter)
+	if len(stuck) > 0 {
+		lines := []string{}
+		for _, e := range stuck {
+			lines = append(lines, fmt.Sprintf("%s (%s)", e.Title, now.Sub(e.Time).Round(time.Minute)))
+		}
+		b.Blocks = append(b.Blocks, list(fmt.Sprintf(":hourglass: Stuck for over %s", stuckAfter), lines))
+	}
+
+	return b
+}
+
+// truncated returns the time of the oldest entry if the history is full
+// and doesn't reach back to the start of a digest, so events are missing
+func truncated(entries []history.Entry, size int64, since time.Time) (time.Time, bool) {
+	if len(entries) == 0 || int64(len(entries)) < size {
+		return time.Time{}, false
+	}
+
+	oldest := entries[len(entries)-1].Time
+	return oldest, oldest.After(since)
+}
+
+// groups lists the titles in a digest, either by service or by event
+func groups(groupBy string, entries []history.Entry) []block {
+	order := []string{}
+	lines := map[string][]string{}
+
+	for _, e := range entries {
+		event := ""
+		switch {
+		case e.Type == "Download" && e.Upgrade:
+			event = "Upgraded"
+		case e.Type == "Download":
+			event = "Downloaded"
+		case deletion(e):
+			event = "Deleted"
+		default:
+			continue
+		}
+
+		group, line := e.Service, fmt.Sprintf("%s: %s", event, e.Title)
+		if groupBy == "event" {
+			group, line = event, fmt.Sprintf("%s (%s)", e.Title, e.Service)
+		}
+
+		if _, ok := lines[group]; !ok {
+			order = append(order, group)
+		}
+		lines[group] = append(lines[group], line)
+	}
+
+	blocks := []block{}
+	for _, g := range order {
+		blocks = append(blocks, list(g, lines[g]))
+	}
+	return blocks
+}
+
+func list(title string, lines []string) block {
+	if len(lines) > digestItems {
+		more := len(lines) - digestItems
+		lines = append(lines[:digestItems:digestItems], fmt.Sprintf("…and %d more", more))
+	}
+
+	return block{
+		Type: "section",
+		Text: &text{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n• %s", title, strings.Join(lines, "\n• "))},
+	}
+}
+
+// deletion returns true for an entry about something being deleted. Files
+// replaced by an upgrade are left out, as the upgrade is already counted
+func deletion(e history.Entry) bool {
+	switch e.Type {
+	case "MovieDelete", "SeriesDelete":
+		return true
+	case "MovieFileDelete", "EpisodeFileDelete":
+		return e.DeleteReason != "upgrade"
+	}
+	return false
+}
+
+// humanSize formats a size in bytes as GiB
+func humanSize(b int) string {
+	return fmt.Sprintf("%.2f GiB", float64(b)/(1<<30))
+}
@@ -0,0 +1,117 @@
+package slack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/internal/pkg/history"
+)
+
+var digestNow = time.Date(1970, 1, 2, 9, 0, 0, 0, time.UTC)
+
+var lowScore, minScore = -10, 0
+
+var digestEntries = []history.Entry{
+	{Service: "sonarr", ID: 3, Type: "Download", Title: "Show - 1x01 - Pilot", Size: 1 << 30, Tags: []string{"kids"}, Time: digestNow.Add(-time.Hour)},
+	{Service: "radarr", ID: 2, Type: "Download", Title: "Film (1970)", Size: 2 << 30, Upgrade: true, Score: &lowScore, Time: digestNow.Add(-2 * time.Hour)},
+	{Service: "radarr", ID: 2, Type: "MovieFileDelete", Title: "Film (1970)", DeleteReason: "upgrade", Time: digestNow.Add(-2*time.Hour - time.Minute)},
+	{Service: "radarr", ID: 1, Type: "MovieDelete", Title: "Old Film (1950)", Time: digestNow.Add(-3 * time.Hour)},
+	{Service: "radarr", ID: 4, Type: "Grab", Title: "Slow Film (1980)", Time: digestNow.Add(-30 * time.Hour)},
+}
+
+func digestSummary(movies, episodes, upgrades, deletions, size string) block {
+	return block{
+		Type: "section",
+		Fields: &[]text{
+			{Type: "mrkdwn", Text: "*Movies:*\n" + movies},
+			{Type: "mrkdwn", Text: "*Episodes:*\n" + episodes},
+			{Type: "mrkdwn", Text: "*Upgrades:*\n" + upgrades},
+			{Type: "mrkdwn", Text: "*Deletions:*\n" + deletions},
+			{Type: "mrkdwn", Text: "*Size Added:*\n" + size},
+		},
+	}
+}
+
+func TestDigest(t *testing.T) {
+	title := header(":newspaper: Digest: Jan 1 09:00 to Jan 2 09:00")
+	stuck := list(":hourglass: Stuck for over 24h0m0s", []string{"Slow Film (1980) (30h0m0s)"})
+
+	tests := map[string]struct {
+		route    config.Route
+		expected []block
+	}{
+		"by service": {
+			route: config.Route{Channel: "c123", Digest: &config.Digest{}},
+			expected: []block{
+				title,
+				digestSummary("0", "1", "1", "1", "3.00 GiB"),
+				list("sonarr", []string{"Downloaded: Show - 1x01 - Pilot"}),
+				list("radarr", []string{"Upgraded: Film (1970)", "Deleted: Old Film (1950)"}),
+				stuck,
+			},
+		},
+		"by event": {
+			route: config.Route{Channel: "c123", Digest: &config.Digest{GroupBy: "event"}},
+			expected: []block{
+				title,
+				digestSummary("0", "1", "1", "1", "3.00 GiB"),
+				list("Downloaded", []string{"Show - 1x01 - Pilot (sonarr)"}),
+				list("Upgraded", []string{"Film (1970) (radarr)"}),
+				list("Deleted", []string{"Old Film (1950) (radarr)"}),
+				stuck,
+			},
+		},
+		"filtered route": {
+			route: config.Route{Channel: "c123", Services: []string{"sonarr"}, Digest: &config.Digest{StuckAfter: config.Duration{Duration: time.Hour}}},
+			expected: []block{
+				title,
+				digestSummary("0", "1", "0", "0", "1.00 GiB"),
+				list("sonarr", []string{"Downloaded: Show - 1x01 - Pilot"}),
+			},
+		},
+		"tagged route": {
+			route: config.Route{Channel: "c123", Tags: []string{"kids"}, Digest: &config.Digest{}},
+			expected: []block{
+				title,
+				digestSummary("0", "1", "0", "0", "1.00 GiB"),
+				list("sonarr", []string{"Downloaded: Show - 1x01 - Pilot"}),
+			},
+		},
+		"scored route": {
+			route: config.Route{Channel: "c123", MinScore: &minScore, SkipTags: []string{"kids"}, Digest: &config.Digest{}},
+			expected: []block{
+				title,
+				digestSummary("0", "0", "0", "1", "0.00 GiB"),
+				list("radarr", []string{"Deleted: Old Film (1950)"}),
+				stuck,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		actual := digest(tc.route, digestEntries, digestNow.Add(-24*time.Hour), digestNow)
+		assert.Equal(t, "c123", actual.Channel, name)
+		assert.Equal(t, tc.expected, actual.Blocks, name)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	since := time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC)
+	entries := []history.Entry{{Time: since.Add(2 * time.Hour)}, {Time: since.Add(time.Hour)}}
+
+	from, ok := truncated(entries, 2, since)
+	assert.True(t, ok)
+	assert.Equal(t, since.Add(time.Hour), from)
+
+	_, ok = truncated(entries, 3, since)
+	assert.False(t, ok, "history isn't full")
+
+	_, ok = truncated(entries, 2, since.Add(90*time.Minute))
+	assert.False(t, ok, "history reaches the start of the digest")
+
+	_, ok = truncated(nil, 0, since)
+	assert.False(t, ok)
+}
@@ -0,0 +1,52 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+)
+
+// packEpisodes is the most episodes of a pack listed in a message
+const packEpisodes = 10
+
+// packMessage builds the message for a file imported from a pack. The
+// message is about the whole pack, so it keeps the pack's title and lists
+// all of its episodes as each file is imported
+func packMessage(r config.Route, e *arr.Event, rec *record) body {
+	b := message(r, e, rec.TS)
+	b.Blocks[0].Text.Text = strings.Replace(b.Blocks[0].Text.Text, e.Title, rec.Title, 1)
+	return withEpisodeList(rec.Episodes, b)
+}
+
+// withEpisodes lists the episodes of a multi-episode webhook under its
+// message
+func withEpisodes(e *arr.Event, b body) body {
+	return withEpisodeList(e.Subject.Episodes, b)
+}
+
+// withEpisodeList lists episodes under a message. Slack collapses long
+// sections, and very long lists are cut short
+func withEpisodeList(eps []arr.Episode, b body) body {
+	if len(eps) < 2 {
+		return b
+	}
+
+	lines := []string{}
+	for _, ep := range eps {
+		lines = append(lines, fmt.Sprintf("%dx%02d - %s", ep.SeasonNumber, ep.EpisodeNumber, ep.Title))
+	}
+
+	if len(lines) > packEpisodes {
+		more := len(lines) - packEpisodes
+		lines = append(lines[:packEpisodes:packEpisodes], fmt.Sprintf("…and %d more", more))
+	}
+
+	b.Blocks = append(b.Blocks, block{
+		Type: "section",
+		Text: &text{Type: "mrkdwn", Text: strings.Join(lines, "\n")},
+	})
+
+	return b
+}
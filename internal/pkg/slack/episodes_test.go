@@ -0,0 +1,88 @@
+package slack
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/internal/pkg/sonarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+func seasonPack(n int) sonarr.Data {
+	d := sonarr.Data{
+		Series:     &sonarr.Series{ID: 1, Title: "Show"},
+		Release:    &sonarr.Release{Quality: "WEBDL-1080p", ReleaseGroup: "legit", ReleaseTitle: "Show.S03.1080p.WEB-DL-legit"},
+		DownloadID: "ABC123",
+		EventType:  arrhook.EventGrab,
+	}
+	for i := 1; i <= n; i++ {
+		d.Episodes = append(d.Episodes, sonarr.Episode{ID: 100 + i, SeasonNumber: 3, EpisodeNumber: i, Title: fmt.Sprintf("Part %d", i)})
+	}
+	return d
+}
+
+func TestWithEpisodes(t *testing.T) {
+	grab := seasonPack(12)
+
+	actual := withEpisodes(grab.Event(), body{})
+	assert.Equal(t, []block{{
+		Type: "section",
+		Text: &text{Type: "mrkdwn", Text: "3x01 - Part 1\n3x02 - Part 2\n3x03 - Part 3\n3x04 - Part 4\n3x05 - Part 5\n" +
+			"3x06 - Part 6\n3x07 - Part 7\n3x08 - Part 8\n3x09 - Part 9\n3x10 - Part 10\n…and 2 more"},
+	}}, actual.Blocks)
+
+	assert.Equal(t, body{}, withEpisodes(sonarrOnDownload.Event(), body{}))
+}
+
+func TestAdvancePack(t *testing.T) {
+	grab := seasonPack(2)
+	rec := (&record{}).advance(grab.Event(), added)
+	assert.Equal(t, "Show - Season 3 (2 episodes)", rec.Title)
+	assert.True(t, rec.pack())
+
+	first := seasonPack(2)
+	first.EventType, first.Episodes = arrhook.EventDownload, first.Episodes[:1]
+	first.EpisodeFile = &sonarr.EpisodeFile{Quality: "WEBDL-1080p", ReleaseGroup: "legit"}
+	rec = rec.advance(first.Event(), added.Add(time.Hour))
+	assert.Equal(t, []arr.Episode{{ID: 101, SeasonNumber: 3, EpisodeNumber: 1, Title: "Part 1"}}, rec.Imported)
+	assert.Contains(t, timeline(rec).Elements[0].Text, "Downloaded 1/2 11:02")
+
+	// The same file can be imported again, like after a failed import
+	rec = rec.advance(first.Event(), added.Add(time.Hour))
+	assert.Len(t, rec.Imported, 1)
+
+	second := seasonPack(2)
+	second.EventType, second.Episodes = arrhook.EventDownload, second.Episodes[1:]
+	second.EpisodeFile = &sonarr.EpisodeFile{Quality: "WEBDL-1080p", ReleaseGroup: "legit"}
+	rec = rec.advance(second.Event(), added.Add(2*time.Hour))
+
+	assert.Equal(t, []entry{
+		{Type: arrhook.EventGrab, Time: added, Detail: "WEBDL-1080p, legit"},
+		{Type: arrhook.EventDownload, Time: added.Add(2 * time.Hour), Detail: "WEBDL-1080p, legit"},
+	}, rec.States)
+
+	assert.Equal(t, []arr.Episode{{ID: 101, SeasonNumber: 3, EpisodeNumber: 1, Title: "Part 1"}, {ID: 102, SeasonNumber: 3, EpisodeNumber: 2, Title: "Part 2"}}, rec.Episodes)
+	assert.Equal(t, rec.Episodes, rec.Imported)
+	assert.Contains(t, timeline(rec).Elements[0].Text, "Downloaded 12:02 (2h0m)")
+}
+
+func TestPackMessage(t *testing.T) {
+	second := seasonPack(2)
+	second.EventType, second.Episodes = arrhook.EventDownload, second.Episodes[1:]
+	second.EpisodeFile = &sonarr.EpisodeFile{Quality: "WEBDL-1080p", ReleaseGroup: "legit"}
+	second.CustomFormatInfo = &sonarr.CustomFormatInfo{CustomFormatScore: 25}
+	rec := &record{TS: "1234", Title: "Show - Season 3 (2 episodes)", Episodes: []arr.Episode{{SeasonNumber: 3, EpisodeNumber: 1, Title: "Part 1"}, {SeasonNumber: 3, EpisodeNumber: 2, Title: "Part 2"}}}
+
+	actual := packMessage(config.Route{Channel: "c123", DeepLinks: true}, second.Event(), rec)
+
+	assert.Equal(t, "1234", actual.TS)
+	assert.Equal(t, ":large_green_circle: Downloaded: Show - Season 3 (2 episodes)", actual.Blocks[0].Text.Text)
+	assert.Equal(t, "/series/show#season-3", actual.Blocks[1].Text.Text, "deep links are kept")
+	assert.Equal(t, text{Type: "mrkdwn", Text: "*Custom Formats:*\nNone (score +25)"}, (*actual.Blocks[3].Fields)[2], "custom formats are kept")
+	assert.Equal(t, "3x01 - Part 1\n3x02 - Part 2", actual.Blocks[len(actual.Blocks)-1].Text.Text)
+}
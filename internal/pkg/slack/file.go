@@ -0,0 +1,73 @@
+package slack
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+)
+
+// reasons maps an *arr delete reason to something readable
+var reasons = map[string]string{
+	"upgrade":          "Upgraded",
+	"missingFromDisk":  "Missing from disk",
+	"manual":           "Deleted manually",
+	"manualOverride":   "Manual override",
+	"noLinkedEpisodes": "No linked episodes",
+}
+
+// postFileDelete posts a deleted file. Files deleted by an upgrade are
+// part of the item's lifecycle, so they reply to its message if there is one
+func (sc *Client) postFileDelete(r config.Route, e *arr.Event) error {
+	b := onFileDeleteInfo(r.Channel, e)
+
+	if e.DeleteReason == "upgrade" {
+		if ts := sc.load(r, e).TS; ts != "" {
+			b = onUpgradeFileDeleteInfo(r.Channel, e, ts)
+		}
+	}
+
+	response, err := sc.send(r, "chat.postMessage", b)
+	if err != nil {
+		return err
+	}
+
+	if !response.OK {
+		slog.Error(response.Error)
+	}
+
+	return nil
+}
+
+func onFileDeleteInfo(c string, e *arr.Event) body {
+	b := base(c, e)
+	b.Blocks[0].Text.Text = fmt.Sprintf(":wastebasket: File deleted: %s", e.Title)
+	b.Blocks = append(b.Blocks, block{Type: "section", Fields: fileDeleteFields(e)})
+	return b
+}
+
+func onUpgradeFileDeleteInfo(c string, e *arr.Event, ts string) body {
+	return body{
+		Channel:  c,
+		ThreadTS: ts,
+		Text:     fmt.Sprintf("Old file deleted: %s", e.Title),
+		Blocks: []block{
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: ":wastebasket: Old file deleted for upgrade"}},
+			{Type: "section", Fields: fileDeleteFields(e)},
+		},
+	}
+}
+
+func fileDeleteFields(e *arr.Event) *[]text {
+	why, ok := reasons[e.DeleteReason]
+	if !ok {
+		why = "Unknown"
+	}
+
+	return &[]text{
+		{Type: "mrkdwn", Text: "*Reason:*\n" + why},
+		{Type: "mrkdwn", Text: "*Quality:*\n" + e.Quality()},
+		{Type: "mrkdwn", Text: "*Size:*\n" + humanSize(e.Size())},
+	}
+}
@@ -0,0 +1,76 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/radarr"
+	"github.com/mbarrin/gwarr/internal/pkg/sonarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+var sonarrOnFileDelete = sonarr.Data{
+	Series: &sonarr.Series{
+		ID:     123,
+		Title:  "Name Of Show!",
+		IMDBID: "tt10574558",
+	},
+	Episodes: []sonarr.Episode{
+		{ID: 555, Title: "title", SeasonNumber: 4, EpisodeNumber: 1, AirDate: "1970-01-01"},
+	},
+	EpisodeFile:    &sonarr.EpisodeFile{Quality: "WEBDL-720p", Size: 3 << 29},
+	DeleteReason:   "upgrade",
+	EventType:      arrhook.EventEpisodeFileDelete,
+	ApplicationURL: "http://localhost",
+}
+
+var fileDeleteFieldsUpgrade = &[]text{
+	{Type: "mrkdwn", Text: "*Reason:*\nUpgraded"},
+	{Type: "mrkdwn", Text: "*Quality:*\nWEBDL-720p"},
+	{Type: "mrkdwn", Text: "*Size:*\n1.50 GiB"},
+}
+
+func TestOnFileDeleteInfo(t *testing.T) {
+	actual := onFileDeleteInfo("c123", sonarrOnFileDelete.Event())
+
+	assert.Equal(t, ":wastebasket: File deleted: Name Of Show! - 4x01 - title", actual.Blocks[0].Text.Text)
+	assert.Equal(t, block{Type: "section", Fields: fileDeleteFieldsUpgrade}, actual.Blocks[3])
+
+	missing := sonarrOnFileDelete
+	missing.DeleteReason = "missingFromDisk"
+	actual = onFileDeleteInfo("c123", missing.Event())
+	assert.Equal(t, "*Reason:*\nMissing from disk", (*actual.Blocks[3].Fields)[0].Text)
+}
+
+func TestOnMovieFileDeleteInfo(t *testing.T) {
+	deleted := radarr.Data{
+		Movie:          &radarr.Movie{ID: 686, Title: "Film", Year: 1970, TMDBID: 123},
+		MovieFile:      &radarr.MovieFile{Quality: "Bluray-1080p", Size: 1 << 30},
+		DeleteReason:   "manual",
+		EventType:      arrhook.EventMovieFileDelete,
+		ApplicationURL: "http://localhost",
+	}
+
+	actual := onFileDeleteInfo("c123", deleted.Event())
+	assert.Equal(t, ":wastebasket: File deleted: Film (1970)", actual.Blocks[0].Text.Text)
+	assert.Equal(t, &[]text{
+		{Type: "mrkdwn", Text: "*Reason:*\nDeleted manually"},
+		{Type: "mrkdwn", Text: "*Quality:*\nBluray-1080p"},
+		{Type: "mrkdwn", Text: "*Size:*\n1.00 GiB"},
+	}, actual.Blocks[3].Fields)
+}
+
+func TestOnUpgradeFileDeleteInfo(t *testing.T) {
+	expected := body{
+		Channel:  "c123",
+		ThreadTS: "1234",
+		Text:     "Old file deleted: Name Of Show! - 4x01 - title",
+		Blocks: []block{
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: ":wastebasket: Old file deleted for upgrade"}},
+			{Type: "section", Fields: fileDeleteFieldsUpgrade},
+		},
+	}
+
+	assert.Equal(t, expected, onUpgradeFileDeleteInfo("c123", sonarrOnFileDelete.Event(), "1234"))
+}
@@ -0,0 +1,161 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+// levels maps a health check level to its emoji
+var levels = map[string]string{
+	"ok":      ":white_check_mark:",
+	"notice":  ":information_source:",
+	"warning": ":warning:",
+	"error":   ":rotating_light:",
+}
+
+// issue defines the message posted for an ongoing health check
+type issue struct {
+	TS    string    `json:"ts,omitempty"`
+	Since time.Time `json:"since"`
+}
+
+// healthKey returns the cache hash holding ongoing health checks for a route
+func healthKey(r config.Route, e *arr.Event) string {
+	return "health:" + e.Source + ":" + r.Name
+}
+
+// postHealth posts a health check to a route. Repeats of an ongoing
+// check update its message rather than posting again, and when the check
+// is restored the message is updated to show how long it lasted
+func (sc *Client) postHealth(r config.Route, e *arr.Event) error {
+	h := e.Health
+	if h == nil {
+		return nil
+	}
+	key := healthKey(r, e)
+	now := time.Now()
+
+	var ongoing *issue
+	raw, err := sc.redis.HGet(ctx, key, h.Type).Result()
+	if err == nil {
+		ongoing = &issue{}
+		if err := json.Unmarshal([]byte(raw), ongoing); err != nil {
+			slog.Error(err.Error())
+			ongoing = nil
+		}
+	}
+
+	var b body
+	switch {
+	case e.Kind == arrhook.EventHealthRestored && ongoing != nil:
+		b = onHealthRestoredInfo(r.Channel, e, h, ongoing.TS, now.Sub(ongoing.Since))
+	case e.Kind == arrhook.EventHealthRestored:
+		b = onHealthRestoredInfo(r.Channel, e, h, "", 0)
+	case ongoing != nil && r.Webhook != "":
+		// A webhook can't update the original, and posting again is noise
+		return nil
+	case ongoing != nil:
+		b = onHealthInfo(r.Channel, e, h, ongoing.TS)
+	default:
+		ongoing = &issue{Since: now}
+		b = onHealthInfo(r.Channel, e, h, "")
+	}
+
+	method := "chat.postMessage"
+	if b.TS != "" {
+		method = "chat.update"
+	}
+
+	response, err := sc.send(r, method, b)
+	if err != nil {
+		return err
+	}
+
+	if !response.OK {
+		slog.Error(response.Error)
+		return nil
+	}
+
+	if e.Kind == arrhook.EventHealthRestored {
+		err = sc.redis.HDel(ctx, key, h.Type).Err()
+	} else {
+		ongoing.TS = response.TS
+		jb, _ := json.Marshal(ongoing)
+		err = sc.redis.HSet(ctx, key, h.Type, jb).Err()
+	}
+	if err != nil {
+		slog.Error(err.Error())
+	}
+
+	return nil
+}
+
+func onHealthInfo(c string, e *arr.Event, h *arr.Health, ts string) body {
+	emoji, ok := levels[h.Level]
+	if !ok {
+		emoji = levels["warning"]
+	}
+
+	b := healthBase(c, e, h, ts)
+	b.Text = fmt.Sprintf("%s health: %s", service(e), h.Message)
+	b.Blocks[0].Text.Text = fmt.Sprintf("%s %s health: %s", emoji, service(e), h.Type)
+	return b
+}
+
+func onHealthRestoredInfo(c string, e *arr.Event, h *arr.Health, ts string, lasted time.Duration) body {
+	b := healthBase(c, e, h, ts)
+	b.Text = fmt.Sprintf("%s health resolved: %s", service(e), h.Message)
+	b.Blocks[0].Text.Text = fmt.Sprintf("%s Resolved: %s health: %s", levels["ok"], service(e), h.Type)
+	b.Blocks[1].Text.Text = fmt.Sprintf("~%s~", h.Message)
+	if lasted > 0 {
+		b.Blocks = append(b.Blocks, block{
+			Type:     "context",
+			Elements: []text{{Type: "mrkdwn", Text: "Lasted " + duration(lasted)}},
+		})
+	}
+	return b
+}
+
+func healthBase(c string, e *arr.Event, h *arr.Health, ts string) body {
+	b := body{
+		Channel: c,
+		TS:      ts,
+		Blocks: []block{
+			{
+				Type: "header",
+				Text: &text{Type: "plain_text", Emoji: true},
+			},
+			{
+				Type: "section",
+				Text: &text{Type: "mrkdwn", Text: h.Message},
+			},
+		},
+	}
+
+	if h.WikiURL != "" {
+		b.Blocks[1].Accessory = &element{
+			Type:  "button",
+			Text:  &text{Type: "plain_text", Text: "Wiki"},
+			URL:   h.WikiURL,
+			Value: h.Type,
+		}
+	}
+
+	return b
+}
+
+// service returns the display name of the service a webhook came from
+func service(e *arr.Event) string {
+	s := e.Source
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
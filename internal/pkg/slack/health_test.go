@@ -0,0 +1,103 @@
+package slack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/internal/pkg/radarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+var radarrOnHealth = radarr.Data{
+	EventType:  arrhook.EventHealth,
+	Level:      "error",
+	Message:    "Indexers unavailable",
+	HealthType: "IndexerStatusCheck",
+	WikiURL:    "https://wiki.servarr.com/radarr/system",
+}
+
+var wikiButton = &element{
+	Type:  "button",
+	Text:  &text{Type: "plain_text", Text: "Wiki"},
+	URL:   "https://wiki.servarr.com/radarr/system",
+	Value: "IndexerStatusCheck",
+}
+
+func TestOnHealthInfo(t *testing.T) {
+	h := radarrOnHealth.Event().Health
+
+	expected := body{
+		Channel: "c123",
+		TS:      "1234",
+		Text:    "Radarr health: Indexers unavailable",
+		Blocks: []block{
+			{Type: "header", Text: &text{Type: "plain_text", Text: ":rotating_light: Radarr health: IndexerStatusCheck", Emoji: true}},
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: "Indexers unavailable"}, Accessory: wikiButton},
+		},
+	}
+
+	assert.Equal(t, expected, onHealthInfo("c123", radarrOnHealth.Event(), h, "1234"))
+}
+
+func TestOnHealthRestoredInfo(t *testing.T) {
+	h := &arr.Health{Level: "error", Message: "Indexers unavailable", Type: "IndexerStatusCheck", WikiURL: "https://wiki.servarr.com/radarr/system"}
+
+	expected := body{
+		Channel: "c123",
+		TS:      "1234",
+		Text:    "Radarr health resolved: Indexers unavailable",
+		Blocks: []block{
+			{Type: "header", Text: &text{Type: "plain_text", Text: ":white_check_mark: Resolved: Radarr health: IndexerStatusCheck", Emoji: true}},
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: "~Indexers unavailable~"}, Accessory: wikiButton},
+			{Type: "context", Elements: []text{{Type: "mrkdwn", Text: "Lasted 2h5m"}}},
+		},
+	}
+
+	assert.Equal(t, expected, onHealthRestoredInfo("c123", radarrOnHealth.Event(), h, "1234", 2*time.Hour+5*time.Minute))
+}
+
+func TestPostHealth(t *testing.T) {
+	r := config.Route{Name: "ops", Channel: "c123"}
+	sc, stub := newStubClient(t, []config.Route{r})
+
+	restored := radarrOnHealth
+	restored.EventType = arrhook.EventHealthRestored
+
+	assert.NoError(t, sc.postHealth(r, radarrOnHealth.Event()))
+	assert.NoError(t, sc.postHealth(r, radarrOnHealth.Event()))
+	assert.NoError(t, sc.postHealth(r, restored.Event()))
+	assert.NoError(t, sc.postHealth(r, restored.Event()))
+
+	calls := stub.Calls()
+	assert.Len(t, calls, 4)
+
+	assert.Equal(t, "chat.postMessage", calls[0].Method)
+	assert.Equal(t, "", calls[0].Body.TS)
+
+	assert.Equal(t, "chat.update", calls[1].Method, "a repeat updates the ongoing message")
+	assert.Equal(t, "5678", calls[1].Body.TS)
+
+	assert.Equal(t, "chat.update", calls[2].Method, "the restore updates the ongoing message")
+	assert.Equal(t, "5678", calls[2].Body.TS)
+	assert.Equal(t, ":white_check_mark: Resolved: Radarr health: IndexerStatusCheck", calls[2].Body.Blocks[0].Text.Text)
+	assert.Len(t, calls[2].Body.Blocks, 3, "the restore says how long it lasted")
+
+	assert.Equal(t, "chat.postMessage", calls[3].Method, "a restore without an ongoing check posts")
+	assert.Equal(t, "", calls[3].Body.TS)
+	assert.Len(t, calls[3].Body.Blocks, 2)
+}
+
+func TestPostHealthWebhook(t *testing.T) {
+	r := config.Route{Name: "ops", Channel: "c123"}
+	sc, stub := newStubClient(t, []config.Route{r})
+
+	assert.NoError(t, sc.postHealth(r, radarrOnHealth.Event()))
+
+	r.Webhook = "http://localhost/hook"
+	assert.NoError(t, sc.postHealth(r, radarrOnHealth.Event()))
+	assert.Len(t, stub.Calls(), 1, "a webhook can't update, so repeats are dropped")
+}
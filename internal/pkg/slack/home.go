@@ -0,0 +1,217 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mbarrin/gwarr/internal/pkg/history"
+)
+
+const (
+	// homeDownloads is the number of downloads shown on the Home tab
+	homeDownloads = 10
+	// stuckAfter is how long an item can sit in grabbed before it is stuck
+	stuckAfter = 6 * time.Hour
+)
+
+type view struct {
+	Type   string  `json:"type"`
+	Blocks []block `json:"blocks"`
+}
+
+type publish struct {
+	UserID string `json:"user_id"`
+	View   view   `json:"view"`
+}
+
+type event struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge,omitempty"`
+	Event     struct {
+		Type string `json:"type"`
+		User string `json:"user"`
+		Tab  string `json:"tab"`
+	} `json:"event"`
+}
+
+type interaction struct {
+	Type string `json:"type"`
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// HandleEvent handles a request from the Slack Events API and returns
+// the body that should be sent back to Slack
+func (sc *Client) HandleEvent(b []byte) ([]byte, error) {
+	e := event{}
+	err := json.Unmarshal(b, &e)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Type {
+	case "url_verification":
+		return []byte(e.Challenge), nil
+	case "event_callback":
+		if e.Event.Type == "app_home_opened" && e.Event.Tab == "home" {
+			go func() {
+				if err := sc.PublishHome(e.Event.User); err != nil {
+					slog.With("package", "slack").Error(err.Error())
+				}
+			}()
+		}
+	}
+
+	return nil, nil
+}
+
+// HandleInteraction handles a button press on the Home tab
+func (sc *Client) HandleInteraction(payload string) error {
+	i := interaction{}
+	err := json.Unmarshal([]byte(payload), &i)
+	if err != nil {
+		return err
+	}
+
+	if i.Type != "block_actions" {
+		return nil
+	}
+
+	for _, a := range i.Actions {
+		switch a.ActionID {
+		case "subscribe":
+			err = sc.subscribe(i.User.ID, a.Value)
+		case "unsubscribe":
+			err = sc.unsubscribe(i.User.ID, a.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return sc.PublishHome(i.User.ID)
+}
+
+// PublishHome builds the Home tab for a user and publishes it
+func (sc *Client) PublishHome(user string) error {
+	entries, err := sc.history.Entries()
+	if err != nil {
+		return err
+	}
+
+	subs, err := sc.subscriptions(user)
+	if err != nil {
+		return err
+	}
+
+	response, err := sc.call("views.publish", publish{UserID: user, View: homeView(entries, subs, time.Now())})
+	if err != nil {
+		return err
+	}
+
+	if !response.OK {
+		return fmt.Errorf("views.publish failed: %s", response.Error)
+	}
+
+	return nil
+}
+
+func homeView(entries []history.Entry, subs []string, now time.Time) view {
+	v := view{Type: "home"}
+
+	v.Blocks = append(v.Blocks, header(":inbox_tray: Recent downloads"))
+	downloads := history.Downloads(entries, homeDownloads)
+	for _, e := range downloads {
+		v.Blocks = append(v.Blocks, item(e, fmt.Sprintf("%s %s", e.Quality, e.ReleaseGroup), now))
+	}
+	if len(downloads) == 0 {
+		v.Blocks = append(v.Blocks, plain("Nothing downloaded yet"))
+	}
+
+	v.Blocks = append(v.Blocks, block{Type: "divider"}, header(":hourglass: Stuck in grabbed"))
+	stuck := history.Stuck(entries, now, stuckAfter)
+	for _, e := range stuck {
+		b := item(e, e.Quality, now)
+		b.Accessory = button("Subscribe", "subscribe", e.Key())
+		v.Blocks = append(v.Blocks, b)
+	}
+	if len(stuck) == 0 {
+		v.Blocks = append(v.Blocks, plain("Nothing is stuck"))
+	}
+
+	v.Blocks = append(v.Blocks, block{Type: "divider"}, header(":stethoscope: Health"))
+	issues := history.Health(entries)
+	for _, e := range issues {
+		v.Blocks = append(v.Blocks, item(e, e.Service, now))
+	}
+	if len(issues) == 0 {
+		v.Blocks = append(v.Blocks, plain("No health issues"))
+	}
+
+	v.Blocks = append(v.Blocks, block{Type: "divider"}, header(":bell: Your subscriptions"))
+	titles := map[string]string{}
+	for _, e := range history.Latest(entries) {
+		titles[e.Key()] = e.Title
+	}
+	for _, s := range subs {
+		title, ok := titles[s]
+		if !ok {
+			title = s
+		}
+		v.Blocks = append(v.Blocks, block{
+			Type:      "section",
+			Text:      &text{Type: "mrkdwn", Text: title},
+			Accessory: button("Unsubscribe", "unsubscribe", s),
+		})
+	}
+	if len(subs) == 0 {
+		v.Blocks = append(v.Blocks, plain("Subscribe to a grabbed item to be told when it downloads"))
+	}
+
+	return v
+}
+
+func item(e history.Entry, detail string, now time.Time) block {
+	title := e.Title
+	if e.URL != "" {
+		title = fmt.Sprintf("<%s|%s>", e.URL, e.Title)
+	}
+
+	detail = strings.TrimSpace(detail)
+	if detail != "" {
+		detail += " · "
+	}
+
+	return block{
+		Type: "section",
+		Text: &text{
+			Type: "mrkdwn",
+			Text: fmt.Sprintf("%s\n%s%s ago", title, detail, now.Sub(e.Time).Round(time.Minute)),
+		},
+	}
+}
+
+func header(s string) block {
+	return block{Type: "header", Text: &text{Type: "plain_text", Text: s, Emoji: true}}
+}
+
+func plain(s string) block {
+	return block{Type: "context", Elements: []text{{Type: "mrkdwn", Text: s}}}
+}
+
+func button(label string, action string, value string) *element {
+	return &element{
+		Type:     "button",
+		Text:     &text{Type: "plain_text", Text: label},
+		ActionID: action,
+		Value:    value,
+	}
+}
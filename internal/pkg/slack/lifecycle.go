@@ -0,0 +1,361 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// recordRetention is how long a record is kept after its item is downloaded
+const recordRetention = 30 * 24 * time.Hour
+
+// importedRetention is how long a download is remembered as imported, to
+// drop an ImportComplete sent after its files' Downloads
+const importedRetention = 24 * time.Hour
+
+var grabToImport = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "gwarr_grab_to_import_seconds",
+		Help:    "Time between a release being grabbed and imported.",
+		Buckets: prometheus.ExponentialBuckets(60, 2, 12),
+	},
+	[]string{"service"},
+)
+
+func init() {
+	prometheus.MustRegister(grabToImport)
+}
+
+// record defines the message posted for an item on a route, and every
+// state the item has been through since that message was posted
+type record struct {
+	TS       string        `json:"ts,omitempty"`
+	States   []entry       `json:"states"`
+	Title    string        `json:"title,omitempty"`
+	Episodes []arr.Episode `json:"episodes,omitempty"`
+	Imported []arr.Episode `json:"imported,omitempty"`
+}
+
+type entry struct {
+	Type   arrhook.EventKind `json:"type"`
+	Time   time.Time         `json:"time"`
+	Detail string            `json:"detail,omitempty"`
+}
+
+// field returns the field holding an item's record. Packs are kept under
+// their download ID, so a pack is one message from grab to import
+func (sc *Client) field(r config.Route, e *arr.Event) string {
+	id := fmt.Sprint(e.ID)
+
+	// Series and episode IDs overlap, so series are kept apart
+	if e.Kind == arrhook.EventSeriesAdd || e.Kind == arrhook.EventSeriesDelete {
+		return "series:" + id
+	}
+
+	dl := e.DownloadID
+	if dl == "" {
+		return id
+	}
+
+	key := "download:" + dl
+	if len(e.Subject.Episodes) > 1 {
+		return key
+	}
+
+	// A file from a pack can be imported on its own
+	if ok, _ := sc.redis.HExists(ctx, tsKey(r, e), key).Result(); ok {
+		return key
+	}
+
+	return id
+}
+
+// load reads the record for an item on a route
+func (sc *Client) load(r config.Route, e *arr.Event) *record {
+	raw, err := sc.redis.HGet(ctx, tsKey(r, e), sc.field(r, e)).Result()
+	if err != nil {
+		if rec := sc.loadLegacy(r, e); rec != nil {
+			return rec
+		}
+		slog.Debug(fmt.Sprintf("Could not find record for %s for %s", sc.field(r, e), tsKey(r, e)))
+		return &record{}
+	}
+
+	return decode(raw)
+}
+
+// legacyKeys returns the hashes older versions of gwarr kept records in,
+// newest first. They were kept per channel, and before that per service
+func legacyKeys(r config.Route, e *arr.Event) []string {
+	if r.Webhook != "" {
+		return nil
+	}
+	return []string{e.Source + ":" + r.Channel, e.Source}
+}
+
+// loadLegacy moves an item's record out of an older version's hash, so
+// items in flight during an upgrade keep updating their message. The old
+// field is removed, so only the first route to find it takes it over
+func (sc *Client) loadLegacy(r config.Route, e *arr.Event) *record {
+	field := fmt.Sprint(e.ID)
+	for _, key := range legacyKeys(r, e) {
+		raw, err := sc.redis.HGet(ctx, key, field).Result()
+		if err != nil {
+			continue
+		}
+
+		err = sc.redis.HDel(ctx, key, field).Err()
+		if err != nil {
+			slog.Error(err.Error())
+		}
+		return decode(raw)
+	}
+	return nil
+}
+
+// save writes the record for an item on a route
+func (sc *Client) save(r config.Route, e *arr.Event, rec *record) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	key, field := tsKey(r, e), sc.field(r, e)
+	err = sc.redis.HSet(ctx, key, field, b).Err()
+	if err != nil {
+		slog.Error(err.Error())
+	}
+
+	if last := rec.last(); last != nil && last.Type == arrhook.EventDownload {
+		err = sc.redis.ZAdd(ctx, endedKey(key), redis.Z{Score: float64(last.Time.Unix()), Member: field}).Err()
+	} else {
+		err = sc.redis.ZRem(ctx, endedKey(key), field).Err()
+	}
+	if err != nil {
+		slog.Error(err.Error())
+	}
+
+	sc.prune(key, time.Now())
+}
+
+// forget removes the record for an item on a route
+func (sc *Client) forget(r config.Route, e *arr.Event) {
+	key, field := tsKey(r, e), sc.field(r, e)
+	err := sc.redis.HDel(ctx, key, field).Err()
+	if err != nil {
+		slog.Error(err.Error())
+	}
+
+	err = sc.redis.ZRem(ctx, endedKey(key), field).Err()
+	if err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// endedKey returns the sorted set of records in a hash whose lifecycle
+// has ended, scored by when it ended
+func endedKey(key string) string {
+	return "ended:" + key
+}
+
+// prune removes records whose lifecycle ended longer ago than
+// recordRetention. They are kept until then so deletes and upgrades can
+// still find the original message
+func (sc *Client) prune(key string, now time.Time) {
+	cutoff := fmt.Sprint(now.Add(-recordRetention).Unix())
+	fields, err := sc.redis.ZRangeByScore(ctx, endedKey(key), &redis.ZRangeBy{Min: "-inf", Max: cutoff}).Result()
+	if err != nil || len(fields) == 0 {
+		return
+	}
+
+	_, err = sc.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, key, fields...)
+		pipe.ZRemRangeByScore(ctx, endedKey(key), "-inf", cutoff)
+		return nil
+	})
+	if err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// decode reads a stored record. Records moved from the per-service hash
+// of older versions of gwarr are only the message timestamp, so anything
+// that isn't JSON is treated as that
+func decode(raw string) *record {
+	rec := record{}
+	if err := json.Unmarshal([]byte(raw), &rec); err != nil {
+		return &record{TS: raw}
+	}
+	return &rec
+}
+
+// last returns the most recent state, if there is one
+func (rec *record) last() *entry {
+	if len(rec.States) == 0 {
+		return nil
+	}
+	return &rec.States[len(rec.States)-1]
+}
+
+// pack returns true if the record is for a multi-episode download
+func (rec *record) pack() bool {
+	return len(rec.Episodes) > 1
+}
+
+// imported adds the episodes of an imported file of a pack, once each
+func (rec *record) imported(e *arr.Event) {
+	seen := map[arr.Episode]bool{}
+	for _, ep := range rec.Imported {
+		seen[ep] = true
+	}
+
+	for _, ep := range e.Subject.Episodes {
+		if !seen[ep] {
+			rec.Imported = append(rec.Imported, ep)
+			seen[ep] = true
+		}
+	}
+}
+
+// advance adds the state for a webhook. A new message is started when
+// the previous lifecycle has already ended with a download, unless it is
+// another file from the same pack being imported
+func (rec *record) advance(e *arr.Event, t time.Time) *record {
+	last := rec.last()
+	if last != nil && last.Type == arrhook.EventDownload {
+		if rec.pack() && e.Kind == arrhook.EventDownload {
+			rec.imported(e)
+			last.Time = t
+			return rec
+		}
+		rec = &record{}
+	}
+
+	if len(rec.States) == 0 && len(e.Subject.Episodes) > 1 {
+		rec.Title, rec.Episodes = e.Title, e.Subject.Episodes
+	}
+	if rec.pack() && e.Kind == arrhook.EventDownload {
+		rec.imported(e)
+	}
+
+	state := entry{Type: e.Kind, Time: t}
+	if e.Kind == arrhook.EventGrab || e.Kind == arrhook.EventDownload {
+		state.Detail = strings.Trim(e.Quality()+", "+e.ReleaseGroup(), ", ")
+	}
+
+	rec.States = append(rec.States, state)
+	return rec
+}
+
+// timeline renders the states of a record like
+// "Added 10:02 → Grabbed 10:05 (1080p, GroupX) → Downloaded 10:48 (43m)".
+// A pack still being imported shows how many episodes are in, like
+// "Downloaded 3/10 11:20"
+func timeline(rec *record) block {
+	steps := []string{}
+
+	var grabbed time.Time
+	for i, e := range rec.States {
+		at := e.Time.Local().Format("15:04")
+		if i == 0 || !sameDay(rec.States[i-1].Time, e.Time) {
+			at = e.Time.Local().Format("Jan 2 15:04")
+		}
+
+		label := lifecycle[e.Type].label
+		if e.Type == arrhook.EventDownload && rec.pack() && len(rec.Imported) < len(rec.Episodes) {
+			label = fmt.Sprintf("%s %d/%d", label, len(rec.Imported), len(rec.Episodes))
+		}
+
+		step := fmt.Sprintf("%s %s", label, at)
+		switch {
+		case e.Type == arrhook.EventDownload && !grabbed.IsZero():
+			step += fmt.Sprintf(" (%s)", duration(e.Time.Sub(grabbed)))
+		case e.Detail != "":
+			step += fmt.Sprintf(" (%s)", e.Detail)
+		}
+
+		if e.Type == arrhook.EventGrab {
+			grabbed = e.Time
+		}
+
+		steps = append(steps, step)
+	}
+
+	return block{
+		Type:     "context",
+		Elements: []text{{Type: "mrkdwn", Text: strings.Join(steps, " → ")}},
+	}
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Local().Format(time.DateOnly) == b.Local().Format(time.DateOnly)
+}
+
+// duration formats a duration to the minute, like "1h5m" or "43m"
+func duration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		return "<1m"
+	}
+	return strings.TrimSuffix(d.String(), "0s")
+}
+
+// imported returns true for an ImportComplete whose files were already
+// sent as a Download each, as Sonarr sends both when On Import and On
+// Import Complete are turned on. Each Download marks its release so the
+// ImportComplete after it isn't counted twice
+func (sc *Client) imported(e *arr.Event) bool {
+	if e.Kind != arrhook.EventDownload || e.DownloadID == "" {
+		return false
+	}
+
+	key := "imported:" + e.Source + ":" + e.DownloadID
+	if e.ImportComplete() {
+		return sc.redis.Get(ctx, key).Err() == nil
+	}
+
+	err := sc.redis.Set(ctx, key, 1, importedRetention).Err()
+	if err != nil {
+		slog.Error(err.Error())
+	}
+	return false
+}
+
+// observeImport records how long an item took to import after it was
+// grabbed. Grab times are kept per item rather than per route, so each
+// import is only counted once however many routes it is sent to
+func (sc *Client) observeImport(e *arr.Event, t time.Time) {
+	key := "grabbed:" + e.Source
+
+	switch e.Kind {
+	case arrhook.EventGrab:
+		err := sc.redis.HSet(ctx, key, e.ID, t.Format(time.RFC3339)).Err()
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	case arrhook.EventDownload:
+		raw, err := sc.redis.HGet(ctx, key, fmt.Sprint(e.ID)).Result()
+		if err != nil {
+			return
+		}
+
+		grabbed, err := time.Parse(time.RFC3339, raw)
+		if err == nil {
+			grabToImport.WithLabelValues(e.Source).Observe(t.Sub(grabbed).Seconds())
+		}
+
+		err = sc.redis.HDel(ctx, key, fmt.Sprint(e.ID)).Err()
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	}
+}
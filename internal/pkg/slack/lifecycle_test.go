@@ -0,0 +1,91 @@
+package slack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+var added = time.Date(1970, 1, 1, 10, 2, 0, 0, time.Local)
+
+func TestDecode(t *testing.T) {
+	tests := map[string]struct {
+		raw      string
+		expected *record
+	}{
+		"record":    {raw: `{"ts":"1234","states":[{"type":"Grab","time":"1970-01-01T00:00:00Z"}]}`, expected: &record{TS: "1234", States: []entry{{Type: arrhook.EventGrab, Time: time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)}}}},
+		"timestamp": {raw: "1234.5678", expected: &record{TS: "1234.5678"}},
+	}
+
+	for name, tc := range tests {
+		assert.Equal(t, tc.expected, decode(tc.raw), name)
+	}
+}
+
+func TestLegacyKeys(t *testing.T) {
+	assert.Equal(t, []string{"radarr:c123", "radarr"}, legacyKeys(config.Route{Name: "movies", Channel: "c123"}, radarrOnGrab.Event()))
+	assert.Nil(t, legacyKeys(config.Route{Name: "hook", Webhook: "https://hooks.slack.com/services/a"}, radarrOnGrab.Event()))
+}
+
+func TestAdvance(t *testing.T) {
+	grabbed := (&record{TS: "1234", States: []entry{{Type: arrhook.EventMovieAdded, Time: added}}}).advance(radarrOnGrab.Event(), added.Add(3*time.Minute))
+	assert.Equal(t, &record{
+		TS: "1234",
+		States: []entry{
+			{Type: arrhook.EventMovieAdded, Time: added},
+			{Type: arrhook.EventGrab, Time: added.Add(3 * time.Minute), Detail: "1080p, legit"},
+		},
+	}, grabbed)
+
+	regrabbed := (&record{TS: "1234", States: []entry{{Type: arrhook.EventDownload, Time: added}}}).advance(radarrOnGrab.Event(), added.Add(time.Hour))
+	assert.Equal(t, &record{
+		States: []entry{{Type: arrhook.EventGrab, Time: added.Add(time.Hour), Detail: "1080p, legit"}},
+	}, regrabbed)
+}
+
+func TestTimeline(t *testing.T) {
+	rec := &record{
+		States: []entry{
+			{Type: arrhook.EventMovieAdded, Time: added},
+			{Type: arrhook.EventGrab, Time: added.Add(3 * time.Minute), Detail: "1080p, GroupX"},
+			{Type: arrhook.EventDownload, Time: added.Add(46 * time.Minute), Detail: "1080p, GroupX"},
+		},
+	}
+
+	expected := block{
+		Type:     "context",
+		Elements: []text{{Type: "mrkdwn", Text: "Added Jan 1 10:02 → Grabbed 10:05 (1080p, GroupX) → Downloaded 10:48 (43m)"}},
+	}
+
+	assert.Equal(t, expected, timeline(rec))
+}
+
+func TestDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		10 * time.Second:               "<1m",
+		43 * time.Minute:               "43m",
+		time.Hour + 5*time.Minute + 20: "1h5m",
+	}
+
+	for d, expected := range tests {
+		assert.Equal(t, expected, duration(d))
+	}
+}
+
+func TestImported(t *testing.T) {
+	sc, _ := newStubClient(t, nil)
+
+	download := &arr.Event{Source: "sonarr", Kind: arrhook.EventDownload, Name: "Download", DownloadID: "ABC123"}
+	complete := &arr.Event{Source: "sonarr", Kind: arrhook.EventDownload, Name: "ImportComplete", DownloadID: "ABC123"}
+	other := &arr.Event{Source: "sonarr", Kind: arrhook.EventDownload, Name: "ImportComplete", DownloadID: "DEF456"}
+
+	assert.False(t, sc.imported(complete), "nothing has been imported yet")
+	assert.False(t, sc.imported(download), "a download is always posted")
+	assert.True(t, sc.imported(complete), "the release was already posted file by file")
+	assert.False(t, sc.imported(other))
+}
@@ -0,0 +1,70 @@
+package slack
+
+import (
+	"sync"
+	"time"
+)
+
+// keyLock is the lock for a key, and how many are holding or waiting on it
+type keyLock struct {
+	held    chan struct{}
+	holders int
+}
+
+// keyLocks serializes the updates to each key, like a season whose
+// episodes are imported at the same time or a series' parent message
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+func newKeyLocks() *keyLocks {
+	return &keyLocks{locks: map[string]*keyLock{}}
+}
+
+// lock locks a key, returning the function to unlock it
+func (kl *keyLocks) lock(key string) func() {
+	unlock, _ := kl.acquire(key, nil)
+	return unlock
+}
+
+// lockWithin locks a key unless it is held for longer than wait
+func (kl *keyLocks) lockWithin(key string, wait time.Duration) (func(), bool) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	return kl.acquire(key, timer.C)
+}
+
+// acquire waits for a key's lock until timeout fires, or forever if it is
+// nil. The lock is dropped once nothing holds or waits on it, so only the
+// keys being updated are kept
+func (kl *keyLocks) acquire(key string, timeout <-chan time.Time) (func(), bool) {
+	kl.mu.Lock()
+	l, ok := kl.locks[key]
+	if !ok {
+		l = &keyLock{held: make(chan struct{}, 1)}
+		kl.locks[key] = l
+	}
+	l.holders++
+	kl.mu.Unlock()
+
+	release := func() {
+		kl.mu.Lock()
+		l.holders--
+		if l.holders == 0 {
+			delete(kl.locks, key)
+		}
+		kl.mu.Unlock()
+	}
+
+	select {
+	case l.held <- struct{}{}:
+		return func() {
+			<-l.held
+			release()
+		}, true
+	case <-timeout:
+		release()
+		return nil, false
+	}
+}
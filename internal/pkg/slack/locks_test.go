@@ -0,0 +1,40 @@
+package slack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyLocks(t *testing.T) {
+	kl := newKeyLocks()
+	unlock := kl.lock("sonarr:1:2")
+
+	locked := make(chan bool)
+	go func() {
+		kl.lock("sonarr:1:2")()
+		locked <- true
+	}()
+
+	// Other keys aren't held up
+	kl.lock("sonarr:1:3")()
+
+	select {
+	case <-locked:
+		t.Fatal("key was locked twice")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	_, ok := kl.lockWithin("sonarr:1:2", 10*time.Millisecond)
+	assert.False(t, ok, "gives up on a held key")
+
+	unlock()
+	assert.True(t, <-locked)
+	assert.Empty(t, kl.locks, "locks nothing holds are dropped")
+
+	unlock, ok = kl.lockWithin("sonarr:1:2", 10*time.Millisecond)
+	assert.True(t, ok)
+	unlock()
+	assert.Empty(t, kl.locks)
+}
@@ -0,0 +1,145 @@
+package slack
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+)
+
+// manualKey returns the cache hash holding messages for downloads that
+// need manual interaction on a route, keyed by download ID
+func manualKey(r config.Route, e *arr.Event) string {
+	return "manual:" + e.Source + ":" + r.Name
+}
+
+// postManual posts a download that needs manual interaction. Repeats for
+// the same download update the existing message
+func (sc *Client) postManual(r config.Route, e *arr.Event) error {
+	if e.Interaction == nil {
+		return nil
+	}
+
+	id := e.DownloadID
+	ts := ""
+	if id != "" {
+		ts = sc.redis.HGet(ctx, manualKey(r, e), id).Val()
+	}
+
+	b := onManualInfo(r.Channel, e, e.Interaction, ts)
+
+	method := "chat.postMessage"
+	if ts != "" && r.Webhook == "" {
+		method = "chat.update"
+	}
+
+	response, err := sc.send(r, method, b)
+	if err != nil {
+		return err
+	}
+
+	if !response.OK {
+		slog.Error(response.Error)
+		return nil
+	}
+
+	if id != "" && response.TS != "" {
+		err = sc.redis.HSet(ctx, manualKey(r, e), id, response.TS).Err()
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	}
+
+	return nil
+}
+
+// resolveManual closes the manual interaction message for a download
+// once it has been imported. Webhook routes can't update the warning, so
+// the download message posted to them stands in for it
+func (sc *Client) resolveManual(r config.Route, e *arr.Event) {
+	id := e.DownloadID
+	if id == "" {
+		return
+	}
+
+	ts, err := sc.redis.HGet(ctx, manualKey(r, e), id).Result()
+	if err != nil {
+		return
+	}
+
+	if r.Webhook == "" {
+		response, err := sc.call("chat.update", onManualResolvedInfo(r.Channel, e, ts))
+		if err != nil {
+			slog.With("package", "slack").Error(err.Error())
+			return
+		}
+
+		if !response.OK {
+			slog.Error(response.Error)
+		}
+	}
+
+	err = sc.redis.HDel(ctx, manualKey(r, e), id).Err()
+	if err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func onManualInfo(c string, e *arr.Event, i *arr.Interaction, ts string) body {
+	b := body{
+		Channel: c,
+		TS:      ts,
+		Text:    fmt.Sprintf("Manual interaction required: %s", e.Title),
+		Blocks: []block{
+			header(fmt.Sprintf(":warning: Manual interaction required: %s", e.Title)),
+			{
+				Type: "section",
+				Fields: &[]text{
+					{Type: "mrkdwn", Text: "*Download Client:*\n" + e.DownloadClient},
+					{Type: "mrkdwn", Text: "*Status:*\n" + i.Status},
+				},
+			},
+		},
+	}
+
+	if len(i.Messages) > 0 {
+		b.Blocks = append(b.Blocks, block{
+			Type: "section",
+			Text: &text{Type: "mrkdwn", Text: "• " + strings.Join(i.Messages, "\n• ")},
+		})
+	}
+
+	if i.QueueURL != "" {
+		b.Blocks = append(b.Blocks, block{
+			Type: "section",
+			Text: &text{Type: "mrkdwn", Text: "Open the activity queue to import it"},
+			Accessory: &element{
+				Type: "button",
+				Text: &text{Type: "plain_text", Text: "Activity queue"},
+				URL:  i.QueueURL,
+			},
+		})
+	}
+
+	if i.ReleaseTitle != "" {
+		b.Blocks = append(b.Blocks, block{
+			Type:     "context",
+			Elements: []text{{Type: "mrkdwn", Text: i.ReleaseTitle}},
+		})
+	}
+
+	return b
+}
+
+func onManualResolvedInfo(c string, e *arr.Event, ts string) body {
+	return body{
+		Channel: c,
+		TS:      ts,
+		Text:    fmt.Sprintf("Imported after manual interaction: %s", e.Title),
+		Blocks: []block{
+			header(fmt.Sprintf(":white_check_mark: Imported after manual interaction: %s", e.Title)),
+		},
+	}
+}
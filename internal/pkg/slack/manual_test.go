@@ -0,0 +1,102 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/internal/pkg/radarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+var radarrOnManual = radarr.Data{
+	DownloadInfo:   &radarr.DownloadInfo{Title: "Film.1970.1080p.BluRay-GroupX"},
+	DownloadClient: "qBittorrent",
+	DownloadID:     "ABC123",
+	DownloadStatus: "Warning",
+	StatusMessages: []radarr.StatusMessage{
+		{Title: "Film.1970.1080p.BluRay-GroupX", Messages: []string{"Unknown Movie", "Not an upgrade"}},
+	},
+	EventType:      arrhook.EventManualInteractionRequired,
+	ApplicationURL: "http://localhost",
+}
+
+func TestOnManualInfo(t *testing.T) {
+	expected := body{
+		Channel: "c123",
+		TS:      "1234",
+		Text:    "Manual interaction required: Film.1970.1080p.BluRay-GroupX",
+		Blocks: []block{
+			header(":warning: Manual interaction required: Film.1970.1080p.BluRay-GroupX"),
+			{
+				Type: "section",
+				Fields: &[]text{
+					{Type: "mrkdwn", Text: "*Download Client:*\nqBittorrent"},
+					{Type: "mrkdwn", Text: "*Status:*\nWarning"},
+				},
+			},
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: "• Unknown Movie\n• Not an upgrade"}},
+			{
+				Type: "section",
+				Text: &text{Type: "mrkdwn", Text: "Open the activity queue to import it"},
+				Accessory: &element{
+					Type: "button",
+					Text: &text{Type: "plain_text", Text: "Activity queue"},
+					URL:  "http://localhost/activity/queue",
+				},
+			},
+			{Type: "context", Elements: []text{{Type: "mrkdwn", Text: "Film.1970.1080p.BluRay-GroupX"}}},
+		},
+	}
+
+	assert.Equal(t, expected, onManualInfo("c123", radarrOnManual.Event(), radarrOnManual.Event().Interaction, "1234"))
+}
+
+func TestOnManualResolvedInfo(t *testing.T) {
+	actual := onManualResolvedInfo("c123", radarrOnManual.Event(), "1234")
+
+	assert.Equal(t, "1234", actual.TS)
+	assert.Equal(t, ":white_check_mark: Imported after manual interaction: Film.1970.1080p.BluRay-GroupX", actual.Blocks[0].Text.Text)
+}
+
+func TestPostManual(t *testing.T) {
+	r := config.Route{Name: "alerts", Channel: "c123"}
+	sc, stub := newStubClient(t, []config.Route{r})
+
+	assert.NoError(t, sc.postManual(r, radarrOnManual.Event()))
+	assert.NoError(t, sc.postManual(r, radarrOnManual.Event()))
+
+	calls := stub.Calls()
+	assert.Len(t, calls, 2)
+	assert.Equal(t, slackCall{"chat.postMessage", onManualInfo("c123", radarrOnManual.Event(), radarrOnManual.Event().Interaction, "")}, calls[0])
+	assert.Equal(t, "chat.update", calls[1].Method, "a repeat updates the message")
+	assert.Equal(t, "5678", calls[1].Body.TS)
+
+	unknown := radarrOnManual
+	unknown.DownloadID = ""
+	assert.NoError(t, sc.postManual(r, unknown.Event()))
+	assert.NoError(t, sc.postManual(r, unknown.Event()))
+	assert.Equal(t, "chat.postMessage", stub.Calls()[3].Method, "without a download ID repeats can't be matched")
+}
+
+func TestResolveManual(t *testing.T) {
+	movies := config.Route{Name: "movies", Channel: "c123", Events: []string{"Download"}}
+	alerts := config.Route{Name: "alerts", Channel: "c456", Events: []string{"ManualInteractionRequired"}}
+	sc, stub := newStubClient(t, []config.Route{movies, alerts})
+
+	assert.NoError(t, sc.postManual(alerts, radarrOnManual.Event()))
+
+	download := radarrOnDownload
+	download.DownloadID = radarrOnManual.DownloadID
+	assert.NoError(t, sc.Post(download.Event()))
+
+	updates := filterCalls(stub.Calls(), "chat.update")
+	assert.Equal(t, []slackCall{{"chat.update", onManualResolvedInfo("c456", download.Event(), "5678")}}, updates,
+		"the warning is resolved on a route that doesn't get the download")
+	assert.Equal(t, "", sc.redis.HGet(ctx, manualKey(alerts, download.Event()), download.DownloadID).Val())
+
+	// Resolving again has nothing left to update
+	sc.resolveManual(alerts, download.Event())
+	assert.Equal(t, updates, filterCalls(stub.Calls(), "chat.update"))
+}
@@ -0,0 +1,352 @@
+package slack
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis is an in-process server speaking enough RESP2 for the
+// commands the client uses, so the stateful paths can be tested without
+// a redis-server. Keys don't expire
+type fakeRedis struct {
+	mu      sync.Mutex
+	strings map[string]string
+	hashes  map[string]map[string]string
+	zsets   map[string]map[string]float64
+	sets    map[string]map[string]bool
+	lists   map[string][]string
+}
+
+// newTestRedis starts a fakeRedis and returns a client connected to it
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeRedis{
+		strings: map[string]string{},
+		hashes:  map[string]map[string]string{},
+		zsets:   map[string]map[string]float64{},
+		sets:    map[string]map[string]bool{},
+		lists:   map[string][]string{},
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	rc := redis.NewClient(&redis.Options{Addr: ln.Addr().String()})
+	t.Cleanup(func() {
+		_ = rc.Close()
+		_ = ln.Close()
+	})
+	return rc
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	var queued [][]string
+	multi := false
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		switch name := strings.ToUpper(args[0]); {
+		case name == "MULTI":
+			multi, queued = true, nil
+			w.WriteString("+OK\r\n")
+		case name == "EXEC":
+			fmt.Fprintf(w, "*%d\r\n", len(queued))
+			for _, q := range queued {
+				w.WriteString(f.exec(q))
+			}
+			multi, queued = false, nil
+		case multi:
+			queued = append(queued, args)
+			w.WriteString("+QUEUED\r\n")
+		default:
+			w.WriteString(f.exec(args))
+		}
+
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readCommand reads one command sent as an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("bad command %q", line)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := ""
+	if len(args) > 1 {
+		key = args[1]
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		v, ok := f.strings[key]
+		if !ok {
+			return nilReply
+		}
+		return bulk(v)
+	case "SET":
+		for _, opt := range args[3:] {
+			if _, ok := f.strings[key]; ok && strings.ToUpper(opt) == "NX" {
+				return nilReply
+			}
+		}
+		f.strings[key] = args[2]
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, k := range args[1:] {
+			if f.remove(k) {
+				n++
+			}
+		}
+		return integer(n)
+	case "HGET":
+		v, ok := f.hashes[key][args[2]]
+		if !ok {
+			return nilReply
+		}
+		return bulk(v)
+	case "HEXISTS":
+		if _, ok := f.hashes[key][args[2]]; ok {
+			return integer(1)
+		}
+		return integer(0)
+	case "HSET":
+		if f.hashes[key] == nil {
+			f.hashes[key] = map[string]string{}
+		}
+		n := 0
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, ok := f.hashes[key][args[i]]; !ok {
+				n++
+			}
+			f.hashes[key][args[i]] = args[i+1]
+		}
+		return integer(n)
+	case "HDEL":
+		n := 0
+		for _, field := range args[2:] {
+			if _, ok := f.hashes[key][field]; ok {
+				delete(f.hashes[key], field)
+				n++
+			}
+		}
+		return integer(n)
+	case "HGETALL":
+		var out []string
+		for field, v := range f.hashes[key] {
+			out = append(out, field, v)
+		}
+		return array(out)
+	case "ZADD":
+		if f.zsets[key] == nil {
+			f.zsets[key] = map[string]float64{}
+		}
+		n := 0
+		for i := 2; i+1 < len(args); i += 2 {
+			score, _ := strconv.ParseFloat(args[i], 64)
+			if _, ok := f.zsets[key][args[i+1]]; !ok {
+				n++
+			}
+			f.zsets[key][args[i+1]] = score
+		}
+		return integer(n)
+	case "ZREM":
+		n := 0
+		for _, member := range args[2:] {
+			if _, ok := f.zsets[key][member]; ok {
+				delete(f.zsets[key], member)
+				n++
+			}
+		}
+		return integer(n)
+	case "ZRANGEBYSCORE":
+		return array(f.byScore(key, args[2], args[3]))
+	case "ZREMRANGEBYSCORE":
+		members := f.byScore(key, args[2], args[3])
+		for _, member := range members {
+			delete(f.zsets[key], member)
+		}
+		return integer(len(members))
+	case "SADD":
+		if f.sets[key] == nil {
+			f.sets[key] = map[string]bool{}
+		}
+		n := 0
+		for _, member := range args[2:] {
+			if !f.sets[key][member] {
+				f.sets[key][member] = true
+				n++
+			}
+		}
+		return integer(n)
+	case "SREM":
+		n := 0
+		for _, member := range args[2:] {
+			if f.sets[key][member] {
+				delete(f.sets[key], member)
+				n++
+			}
+		}
+		return integer(n)
+	case "SMEMBERS":
+		var out []string
+		for member := range f.sets[key] {
+			out = append(out, member)
+		}
+		sort.Strings(out)
+		return array(out)
+	case "LPUSH":
+		for _, v := range args[2:] {
+			f.lists[key] = append([]string{v}, f.lists[key]...)
+		}
+		return integer(len(f.lists[key]))
+	case "LTRIM":
+		f.lists[key] = listRange(f.lists[key], args[2], args[3])
+		return "+OK\r\n"
+	case "LRANGE":
+		return array(listRange(f.lists[key], args[2], args[3]))
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+// remove deletes a key of any type
+func (f *fakeRedis) remove(key string) bool {
+	_, s := f.strings[key]
+	_, h := f.hashes[key]
+	_, z := f.zsets[key]
+	_, set := f.sets[key]
+	_, l := f.lists[key]
+	delete(f.strings, key)
+	delete(f.hashes, key)
+	delete(f.zsets, key)
+	delete(f.sets, key)
+	delete(f.lists, key)
+	return s || h || z || set || l
+}
+
+// byScore returns the members of a sorted set within an inclusive range,
+// lowest score first
+func (f *fakeRedis) byScore(key, from, to string) []string {
+	lo, hi := parseScore(from), parseScore(to)
+	var out []string
+	for member, score := range f.zsets[key] {
+		if score >= lo && score <= hi {
+			out = append(out, member)
+		}
+	}
+	slices.SortFunc(out, func(a, b string) int {
+		if c := cmp.Compare(f.zsets[key][a], f.zsets[key][b]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	return out
+}
+
+func parseScore(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// listRange applies Redis' inclusive, negative-aware start and stop
+func listRange(list []string, start, stop string) []string {
+	lo, _ := strconv.Atoi(start)
+	hi, _ := strconv.Atoi(stop)
+	if lo < 0 {
+		lo += len(list)
+	}
+	if hi < 0 {
+		hi += len(list)
+	}
+	lo = max(lo, 0)
+	hi = min(hi, len(list)-1)
+	if lo > hi {
+		return nil
+	}
+	return slices.Clone(list[lo : hi+1])
+}
+
+const nilReply = "$-1\r\n"
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func integer(n int) string {
+	return fmt.Sprintf(":%d\r\n", n)
+}
+
+func array(items []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(items))
+	for _, item := range items {
+		b.WriteString(bulk(item))
+	}
+	return b.String()
+}
@@ -0,0 +1,170 @@
+package slack
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+)
+
+const (
+	// renameWindow is how long renames are collected before posting, so a
+	// whole library being renamed becomes a single message
+	renameWindow = 10 * time.Second
+	// renameFiles is the most renamed files listed in a message
+	renameFiles = 5
+)
+
+type renameQueue struct {
+	mu      sync.Mutex
+	pending map[string][]*arr.Event
+}
+
+func newRenameQueue() *renameQueue {
+	return &renameQueue{pending: map[string][]*arr.Event{}}
+}
+
+// queueRename holds a rename until the window for its service closes
+func (sc *Client) queueRename(e *arr.Event) {
+	sc.renames.mu.Lock()
+	defer sc.renames.mu.Unlock()
+
+	service := e.Source
+	if len(sc.renames.pending[service]) == 0 {
+		time.AfterFunc(renameWindow, func() { sc.flushRenames(service) })
+	}
+	sc.renames.pending[service] = append(sc.renames.pending[service], e)
+}
+
+// flushRenames posts every queued rename for a service, rolled up into
+// a summary when there is more than one
+func (sc *Client) flushRenames(service string) {
+	sc.renames.mu.Lock()
+	batch := sc.renames.pending[service]
+	delete(sc.renames.pending, service)
+	sc.renames.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+	batch = groupRenames(batch)
+
+	for _, r := range sc.routes {
+		if r.DigestOnly || !r.Matches(service, "Rename") {
+			continue
+		}
+
+		routed := renamesFor(r, batch)
+		if len(routed) == 0 {
+			continue
+		}
+
+		b := onRenameInfo(r.Channel, routed[0])
+		if len(routed) > 1 {
+			b = renameSummary(r.Channel, routed)
+		}
+
+		response, err := sc.send(r, "chat.postMessage", b)
+		if err != nil {
+			slog.With("package", "slack").Error(err.Error())
+			continue
+		}
+		if !response.OK {
+			slog.Error(response.Error)
+		}
+	}
+}
+
+// renamesFor returns the renames in a batch whose movie or series has tags
+// the route wants
+func renamesFor(r config.Route, batch []*arr.Event) []*arr.Event {
+	routed := []*arr.Event{}
+	for _, e := range batch {
+		if r.MatchesTags(e.Subject.Tags) {
+			routed = append(routed, e)
+		}
+	}
+	return routed
+}
+
+// groupRenames combines the renames of each movie or series in a batch.
+// Sonarr can send a rename for a series more than once as it works
+// through its seasons
+func groupRenames(batch []*arr.Event) []*arr.Event {
+	grouped := []*arr.Event{}
+	index := map[int]int{}
+	for _, e := range batch {
+		if i, ok := index[e.ID]; ok {
+			grouped[i].Renames = append(grouped[i].Renames, e.Renames...)
+			continue
+		}
+
+		g := *e
+		g.Renames = slices.Clone(e.Renames)
+		index[e.ID] = len(grouped)
+		grouped = append(grouped, &g)
+	}
+	return grouped
+}
+
+func onRenameInfo(c string, e *arr.Event) body {
+	b := base(c, e)
+	b.Blocks[0].Text.Text = fmt.Sprintf(":pencil2: Renamed: %s", e.Title)
+
+	lines := []string{}
+	for _, r := range e.Renames {
+		lines = append(lines, fmt.Sprintf("`%s` → `%s`", r.PreviousRelativePath, r.RelativePath))
+	}
+
+	if len(lines) > renameFiles {
+		more := len(lines) - renameFiles
+		lines = append(lines[:renameFiles:renameFiles], fmt.Sprintf("…and %d more", more))
+	}
+
+	if len(lines) > 0 {
+		b.Blocks = append(b.Blocks, block{
+			Type: "section",
+			Text: &text{Type: "mrkdwn", Text: strings.Join(lines, "\n")},
+		})
+	}
+
+	return b
+}
+
+func renameSummary(c string, batch []*arr.Event) body {
+	files := 0
+	lines := []string{}
+	for _, e := range batch {
+		n := len(e.Renames)
+		files += n
+		lines = append(lines, fmt.Sprintf("<%s|%s> (%s)", e.URL, e.Title, plural(n, "file")))
+	}
+
+	items := plural(len(batch), "movie")
+	if batch[0].Source == "sonarr" {
+		items = fmt.Sprintf("%d series", len(batch))
+	}
+
+	summary := fmt.Sprintf("Renamed %s across %s", plural(files, "file"), items)
+	return body{
+		Channel: c,
+		Text:    summary,
+		Blocks: []block{
+			header(":pencil2: " + summary),
+			list("Renamed", lines),
+		},
+	}
+}
+
+// plural formats a count of things, like "1 file" or "2 files"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,177 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+// SeasonSource reports how many of a season's monitored episodes have
+// been imported, out of how many are monitored
+type SeasonSource interface {
+	SeasonProgress(seriesID int, season int) (int, int, error)
+}
+
+// season defines what is known about a season of a series
+type season struct {
+	Imported []int `json:"imported"`
+	// Total is the number of episodes in the season, when a season pack
+	// has told us
+	Total int `json:"total,omitempty"`
+	// Announced is how many episodes the completion message counted
+	Announced int `json:"announced,omitempty"`
+	// TS holds the completion message posted to each route
+	TS map[string]string `json:"ts,omitempty"`
+}
+
+// CheckSeasons asks a Sonarr instance whether seasons are complete, rather
+// than relying on season packs to say how many episodes a season has
+func (sc *Client) CheckSeasons(s SeasonSource) {
+	sc.seasons = s
+}
+
+// seasonKey returns the cache hash holding the seasons of a service's series
+func seasonKey(e *arr.Event) string {
+	return "seasons:" + e.Source
+}
+
+// wantsSeasons returns true if any route is sent completed seasons for
+// a webhook's series
+func (sc *Client) wantsSeasons(e *arr.Event) bool {
+	for _, r := range sc.routes {
+		if !r.DigestOnly && r.Matches(e.Source, "SeasonComplete") && r.MatchesTags(e.Subject.Tags) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackSeasons records the episodes imported for each season, and posts
+// a completion message once all of a season has been imported
+func (sc *Client) trackSeasons(e *arr.Event) {
+	if len(e.Subject.Episodes) == 0 || (e.Kind != arrhook.EventGrab && e.Kind != arrhook.EventDownload) || !sc.wantsSeasons(e) {
+		return
+	}
+
+	numbers := map[int][]int{}
+	for _, ep := range e.Subject.Episodes {
+		numbers[ep.SeasonNumber] = append(numbers[ep.SeasonNumber], ep.EpisodeNumber)
+	}
+
+	for n, eps := range numbers {
+		field := fmt.Sprintf("%d:%d", e.Subject.ID, n)
+		unlock := sc.locks.lock(e.Source + ":" + field)
+		s := sc.loadSeason(e, field)
+
+		if e.Kind == arrhook.EventGrab {
+			if e.SeasonPack {
+				s.Total = len(eps)
+				sc.saveSeason(e, field, s)
+			}
+			unlock()
+			continue
+		}
+
+		for _, ep := range eps {
+			if !slices.Contains(s.Imported, ep) {
+				s.Imported = append(s.Imported, ep)
+			}
+		}
+
+		have, total := len(s.Imported), s.Total
+		if sc.seasons != nil {
+			h, t, err := sc.seasons.SeasonProgress(e.Subject.ID, n)
+			if err != nil {
+				slog.With("package", "slack").Error(err.Error())
+			} else {
+				have, total = h, t
+			}
+		}
+
+		if total > 0 && have >= total && have != s.Announced {
+			sc.announceSeason(e, e.Subject.Title, n, have, total, s)
+			s.Announced = have
+		}
+
+		sc.saveSeason(e, field, s)
+		unlock()
+	}
+}
+
+// announceSeason posts a season's completion to every route that wants
+// it, or updates the message if it has been posted before
+func (sc *Client) announceSeason(e *arr.Event, series string, n int, have int, total int, s *season) {
+	if s.TS == nil {
+		s.TS = map[string]string{}
+	}
+
+	for _, r := range sc.routes {
+		if r.DigestOnly || !r.Matches(e.Source, "SeasonComplete") || !r.MatchesTags(e.Subject.Tags) {
+			continue
+		}
+
+		b := onSeasonCompleteInfo(r.Channel, e, series, n, have, total)
+		method := "chat.postMessage"
+		if ts := s.TS[r.Name]; ts != "" && r.Webhook == "" {
+			b.TS = ts
+			method = "chat.update"
+		}
+
+		response, err := sc.send(r, method, b)
+		if err != nil {
+			slog.With("package", "slack").Error(err.Error())
+			continue
+		}
+		if !response.OK {
+			slog.Error(response.Error)
+			continue
+		}
+
+		if response.TS != "" {
+			s.TS[r.Name] = response.TS
+		}
+	}
+}
+
+func (sc *Client) loadSeason(e *arr.Event, field string) *season {
+	s := season{}
+	raw, err := sc.redis.HGet(ctx, seasonKey(e), field).Result()
+	if err != nil {
+		return &s
+	}
+
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		slog.Error(err.Error())
+	}
+	return &s
+}
+
+func (sc *Client) saveSeason(e *arr.Event, field string, s *season) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	err = sc.redis.HSet(ctx, seasonKey(e), field, b).Err()
+	if err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func onSeasonCompleteInfo(c string, e *arr.Event, series string, n int, have int, total int) body {
+	summary := fmt.Sprintf("Season %d of %s is complete", n, series)
+	return body{
+		Channel: c,
+		Text:    fmt.Sprintf("%s (%d/%d episodes)", summary, have, total),
+		Blocks: []block{
+			header(":trophy: " + summary),
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: e.URL}},
+			plain(fmt.Sprintf("%d/%d episodes imported", have, total)),
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package slack
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/internal/pkg/sonarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+func TestOnSeasonCompleteInfo(t *testing.T) {
+	expected := body{
+		Channel: "c123",
+		Text:    "Season 2 of Name Of Show! is complete (10/10 episodes)",
+		Blocks: []block{
+			header(":trophy: Season 2 of Name Of Show! is complete"),
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: sonarrOnDownload.URL()}},
+			plain("10/10 episodes imported"),
+		},
+	}
+
+	assert.Equal(t, expected, onSeasonCompleteInfo("c123", sonarrOnDownload.Event(), "Name Of Show!", 2, 10, 10))
+}
+
+func TestWantsSeasons(t *testing.T) {
+	sc := Client{routes: []config.Route{{Name: "tv", Events: []string{"Download"}}}}
+	assert.False(t, sc.wantsSeasons(sonarrOnDownload.Event()))
+
+	sc.routes = append(sc.routes, config.Route{Name: "digest", DigestOnly: true, Events: []string{"SeasonComplete"}})
+	assert.False(t, sc.wantsSeasons(sonarrOnDownload.Event()))
+
+	sc.routes = append(sc.routes, config.Route{Name: "seasons", Events: []string{"SeasonComplete"}})
+	assert.True(t, sc.wantsSeasons(sonarrOnDownload.Event()))
+}
+
+// fakeSeasons is a SeasonSource reporting the same progress for every season
+type fakeSeasons struct {
+	mu          sync.Mutex
+	have, total int
+}
+
+func (f *fakeSeasons) SeasonProgress(int, int) (int, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.have, f.total, nil
+}
+
+func (f *fakeSeasons) set(have, total int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.have, f.total = have, total
+}
+
+func TestTrackSeasons(t *testing.T) {
+	r := config.Route{Name: "seasons", Channel: "c123", Events: []string{"SeasonComplete"}}
+	sc, stub := newStubClient(t, []config.Route{r})
+	progress := &fakeSeasons{}
+	sc.CheckSeasons(progress)
+
+	progress.set(1, 2)
+	sc.trackSeasons(sonarrOnDownload.Event())
+	assert.Empty(t, stub.Calls(), "the season isn't complete")
+
+	// Episodes imported together all see the season complete
+	progress.set(2, 2)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sc.trackSeasons(sonarrOnDownload.Event())
+		}()
+	}
+	wg.Wait()
+
+	calls := stub.Calls()
+	assert.Equal(t, []slackCall{{"chat.postMessage", onSeasonCompleteInfo("c123", sonarrOnDownload.Event(), "Name Of Show!", 4, 2, 2)}}, calls, "the completion is posted once")
+	assert.Empty(t, sc.locks.locks, "idle season locks are dropped")
+
+	// An episode added to a complete season updates the message
+	progress.set(3, 3)
+	sc.trackSeasons(sonarrOnDownload.Event())
+
+	calls = stub.Calls()
+	assert.Len(t, calls, 2)
+	assert.Equal(t, "chat.update", calls[1].Method)
+	assert.Equal(t, "5678", calls[1].Body.TS)
+	assert.Equal(t, "Season 4 of Name Of Show! is complete (3/3 episodes)", calls[1].Body.Text)
+}
+
+func TestTrackSeasonsPack(t *testing.T) {
+	r := config.Route{Name: "seasons", Channel: "c123", Events: []string{"SeasonComplete"}}
+	sc, stub := newStubClient(t, []config.Route{r})
+
+	grab := sonarrOnDownload
+	grab.EventType = arrhook.EventGrab
+	grab.Release = &sonarr.Release{ReleaseType: "seasonPack"}
+	grab.Episodes = []sonarr.Episode{{ID: 555, SeasonNumber: 4, EpisodeNumber: 1}, {ID: 556, SeasonNumber: 4, EpisodeNumber: 2}}
+	sc.trackSeasons(grab.Event())
+
+	first := sonarrOnDownload
+	second := sonarrOnDownload
+	second.Episodes = []sonarr.Episode{{ID: 556, SeasonNumber: 4, EpisodeNumber: 2}}
+
+	sc.trackSeasons(first.Event())
+	assert.Empty(t, stub.Calls())
+
+	sc.trackSeasons(second.Event())
+	assert.Len(t, stub.Calls(), 1, "the pack says how many episodes the season has")
+}
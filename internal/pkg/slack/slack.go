@@ -9,25 +9,37 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
 	"github.com/mbarrin/gwarr/internal/pkg/cache"
-	"github.com/mbarrin/gwarr/internal/pkg/data"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/internal/pkg/history"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
 	"github.com/redis/go-redis/v9"
 )
 
 var ctx = context.Background()
 
+// historySize is the number of events kept for the App Home tab and digests
+const historySize = 1000
+
 type body struct {
-	Channel string  `json:"channel,omitempty"`
-	Text    string  `json:"text,omitempty"`
-	TS      string  `json:"ts,omitempty"`
-	Blocks  []block `json:"blocks,omitempty"`
+	Channel  string  `json:"channel,omitempty"`
+	Text     string  `json:"text,omitempty"`
+	TS       string  `json:"ts,omitempty"`
+	ThreadTS string  `json:"thread_ts,omitempty"`
+	Blocks   []block `json:"blocks,omitempty"`
 }
 
 type block struct {
-	Type   string  `json:"type,omitempty"`
-	Text   *text   `json:"text,omitempty"`
-	Fields *[]text `json:"fields,omitempty"`
+	Type      string   `json:"type,omitempty"`
+	Text      *text    `json:"text,omitempty"`
+	Fields    *[]text  `json:"fields,omitempty"`
+	Elements  []text   `json:"elements,omitempty"`
+	Accessory *element `json:"accessory,omitempty"`
 }
 
 type text struct {
@@ -36,6 +48,14 @@ type text struct {
 	Emoji bool   `json:"emoji,omitempty"`
 }
 
+type element struct {
+	Type     string `json:"type,omitempty"`
+	Text     *text  `json:"text,omitempty"`
+	ActionID string `json:"action_id,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
 type response struct {
 	OK    bool   `json:"ok,omitempty"`
 	TS    string `json:"ts,omitempty"`
@@ -45,14 +65,23 @@ type response struct {
 // Client defines a slack client and associated cache
 type Client struct {
 	url     string
-	channel string
+	routes  []config.Route
 	token   string
+	secret  string
 	client  http.Client
 	redis   *redis.Client
+	history *history.Store
+	renames *renameQueue
+	seasons SeasonSource
+	airing  AiringSource
+	locks   *keyLocks
+	checks  *airingChecks
 }
 
-// New creates a new Slack client
-func New(channel string, token string, redisAddr string) (*Client, error) {
+// New creates a new Slack client that posts to the given routes. The
+// signing secret is only needed to verify requests sent by Slack, and
+// may be empty otherwise
+func New(routes []config.Route, token string, secret string, redisAddr string) (*Client, error) {
 	cache, err := cache.New(redisAddr)
 	if err != nil {
 		return nil, err
@@ -60,10 +89,15 @@ func New(channel string, token string, redisAddr string) (*Client, error) {
 
 	sc := Client{
 		url:     "https://slack.com/api/",
-		channel: channel,
+		routes:  routes,
 		token:   "Bearer " + token,
+		secret:  secret,
 		client:  *http.DefaultClient,
 		redis:   cache,
+		history: history.New(cache, historySize),
+		renames: newRenameQueue(),
+		locks:   newKeyLocks(),
+		checks:  newAiringChecks(),
 	}
 
 	slog.With("package", "slack").Info("Slack client initialised")
@@ -79,76 +113,193 @@ func (sc *Client) newRequest(b []byte, m string) *http.Request {
 	return r
 }
 
-// Post posts a Radarr webhook formatted to a Slack message
-func (sc *Client) Post(d data.Data) error {
-	ts, err := sc.redis.HGet(ctx, d.Service(), fmt.Sprint(d.ID())).Result()
+// Post posts an event formatted as a Slack message to every matching route
+func (sc *Client) Post(e *arr.Event) error {
+	if e.Kind == arrhook.EventTest {
+		return sc.postTest(e)
+	}
+
+	if sc.imported(e) {
+		slog.With("package", "slack").Debug("Release already imported", "downloadId", e.DownloadID)
+		return nil
+	}
+
+	err := sc.history.Add(history.NewEntry(e))
 	if err != nil {
-		slog.Debug(fmt.Sprintf("Could not find timestamp for ID: %d for %s", d.ID(), d.Service()))
+		slog.With("package", "slack").Error(err.Error())
 	}
-	slog.Debug(ts)
 
-	var r *http.Request
-	switch d.Type() {
-	case "MovieAdded":
-		b := onAddInfo(sc.channel, d, ts)
-		jb, err := json.Marshal(b)
-		if err != nil {
-			return err
-		}
+	sc.observeImport(e, e.Received)
+
+	if e.Kind == arrhook.EventRename {
+		sc.queueRename(e)
+		return nil
+	}
 
-		if ts == "" {
-			r = sc.newRequest(jb, "chat.postMessage")
-		} else {
-			r = sc.newRequest(jb, "chat.update")
+	var errs []error
+	posted := false
+	for _, r := range sc.routes {
+		if r.DigestOnly || !r.Matches(e.Source, e.RouteType()) || !r.MatchesScore(e.Score()) || !r.MatchesTags(e.Subject.Tags) {
+			continue
 		}
-	case "Grab":
-		b := onGrabInfo(sc.channel, d, ts)
-		jb, err := json.Marshal(b)
+
+		ts, err := sc.post(r, e)
 		if err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("route %s: %w", r.Name, err))
+			continue
 		}
 
-		if ts == "" {
-			r = sc.newRequest(jb, "chat.postMessage")
-		} else {
-			r = sc.newRequest(jb, "chat.update")
-		}
-	case "Download":
-		b := onDownloadInfo(sc.channel, d, ts)
-		jb, err := json.Marshal(b)
-		if err != nil {
-			return err
+		// Subscribers only need telling once, so reply on the first route
+		if e.Kind == arrhook.EventDownload && ts != "" && !posted {
+			sc.notifySubscribers(r.Channel, e, ts)
+			posted = true
 		}
+	}
 
-		if ts == "" {
-			r = sc.newRequest(jb, "chat.postMessage")
-		} else {
-			r = sc.newRequest(jb, "chat.update")
+	// A route with a manual interaction message for the download gets it
+	// resolved even if it doesn't get the download itself
+	if e.Kind == arrhook.EventDownload {
+		for _, r := range sc.routes {
+			sc.resolveManual(r, e)
 		}
-	case "MovieDelete":
-		b := onDeleteInfo(sc.channel, d)
-		jb, err := json.Marshal(b)
+	}
+
+	// Checking a season can wait on Sonarr, which shouldn't hold up the
+	// response to the webhook
+	go sc.trackSeasons(e)
+
+	return errors.Join(errs...)
+}
+
+// post posts a webhook to a single route and returns the message timestamp
+func (sc *Client) post(r config.Route, e *arr.Event) (string, error) {
+	if e.Kind == arrhook.EventHealth || e.Kind == arrhook.EventHealthRestored {
+		return "", sc.postHealth(r, e)
+	}
+
+	if e.Kind == arrhook.EventMovieFileDelete || e.Kind == arrhook.EventEpisodeFileDelete {
+		return "", sc.postFileDelete(r, e)
+	}
+
+	if e.Kind == arrhook.EventManualInteractionRequired {
+		return "", sc.postManual(r, e)
+	}
+
+	if r.Webhook != "" {
+		return "", sc.postWebhook(r, e)
+	}
+
+	if e.Kind == arrhook.EventMovieDelete || e.Kind == arrhook.EventSeriesDelete {
+		return "", sc.postDelete(r, e)
+	}
+
+	_, tracked := lifecycle[e.Kind]
+	if !tracked {
+		response, err := sc.call("chat.postMessage", message(r, e, ""))
 		if err != nil {
-			return err
+			return "", err
+		}
+		if !response.OK {
+			slog.Error(response.Error)
 		}
+		return "", nil
+	}
+
+	rec := sc.load(r, e).advance(e, time.Now())
+
+	// Each file of a pack is imported on its own, but the message is
+	// about the whole pack
+	b := message(r, e, rec.TS)
+	if rec.pack() && e.Kind == arrhook.EventDownload && len(e.Subject.Episodes) < 2 {
+		b = packMessage(r, e, rec)
+	}
+	b.Blocks = append(b.Blocks, timeline(rec))
+
+	method := "chat.postMessage"
+	if b.TS != "" {
+		method = "chat.update"
+	}
+
+	var p *parent
+	if r.ThreadSeries {
+		p = sc.parent(r, e)
+	}
+	if p != nil && method == "chat.postMessage" {
+		b.ThreadTS = p.TS
+	}
+
+	response, err := sc.call(method, b)
+	if err != nil {
+		return "", err
+	}
+
+	if !response.OK {
+		slog.Error(response.Error)
+		slog.Debug(sc.token)
+		return "", nil
+	}
 
-		r = sc.newRequest(jb, "chat.postMessage")
+	rec.TS = response.TS
+	sc.save(r, e, rec)
+
+	if p != nil {
+		sc.updateParent(r, e, p)
+	}
+
+	return response.TS, nil
+}
+
+// tsKey returns the cache hash holding the record of each item's
+// message on a route
+func tsKey(r config.Route, e *arr.Event) string {
+	return e.Source + ":" + r.Name
+}
+
+// message builds the Slack message for a webhook
+func message(r config.Route, e *arr.Event, ts string) body {
+	c := r.Channel
+
+	var b body
+	switch e.Kind {
+	case arrhook.EventMovieAdded, arrhook.EventSeriesAdd:
+		b = onAddInfo(c, e, ts)
+	case arrhook.EventGrab:
+		b = withEpisodes(e, onGrabInfo(r, e, ts))
+	case arrhook.EventDownload:
+		b = withEpisodes(e, imported(e, onDownloadInfo(c, e, ts)))
+	case arrhook.EventMovieDelete, arrhook.EventSeriesDelete:
+		b = onDeleteInfo(c, e)
+	case arrhook.EventApplicationUpdate:
+		return onUpdateInfo(c, e)
 	default:
-		b := unhandled(sc.channel, d)
-		jb, err := json.Marshal(b)
-		if err != nil {
-			return err
-		}
+		return unhandled(c, e)
+	}
+
+	return tagged(r, e, linked(r, e, b))
+}
+
+// linked points a message's link at the season rather than the series
+// when the route asks for deep links
+func linked(r config.Route, e *arr.Event, b body) body {
+	if r.DeepLinks {
+		b.Blocks[1].Text.Text = e.DeepURL
+	}
+	return b
+}
 
-		r = sc.newRequest(jb, "chat.postMessage")
+// call sends a payload to a Slack API method and decodes the response
+func (sc *Client) call(m string, v any) (*response, error) {
+	jb, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := sc.client.Do(r)
+	resp, err := sc.client.Do(sc.newRequest(jb, m))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
-		err := r.Body.Close()
+		err := resp.Body.Close()
 		if err != nil {
 			slog.With("package", "slack").Error("Failed to close body")
 		}
@@ -160,76 +311,146 @@ func (sc *Client) Post(d data.Data) error {
 
 	err = json.Unmarshal(body, &response)
 	if err != nil {
-		return errors.New("Message sent, but response could not be decoded. Err: " + err.Error())
+		return nil, errors.New("Message sent, but response could not be decoded. Err: " + err.Error())
 	}
 
-	if response.OK {
-		if d.Type() == "MovieDelete" || d.Type() == "Download" {
-			err := sc.redis.HDel(ctx, d.Service(), fmt.Sprint(d.ID())).Err()
-			if err != nil {
-				slog.Error(err.Error())
-			}
-		} else {
-			err := sc.redis.HSet(ctx, d.Service(), d.ID(), response.TS).Err()
-			if err != nil {
-				slog.Error(err.Error())
-			}
-		}
-	} else {
-		slog.Error(response.Error)
-		slog.Debug(sc.token)
+	return &response, nil
+}
+
+func onGrabInfo(r config.Route, e *arr.Event, ts string) body {
+	b := base(r.Channel, e)
+	b.TS = ts
+	b.Blocks[0].Text.Text = fmt.Sprintf(":large_orange_circle: Grabbed: %s", e.Title)
+
+	fields := releaseFields(e, e.Quality(), e.ReleaseGroup())
+	if e.Size() > 0 && r.Shows("size") {
+		*fields = append(*fields, text{Type: "mrkdwn", Text: "*Size:*\n" + humanSize(e.Size())})
+	}
+
+	release := &arr.Release{}
+	if e.Release != nil {
+		release = e.Release
 	}
 
-	return nil
+	if release.Indexer != "" && r.Shows("indexer") {
+		*fields = append(*fields, text{Type: "mrkdwn", Text: "*Indexer:*\n" + release.Indexer})
+	}
+
+	client := e.DownloadClient
+	if client == "" {
+		client = e.DownloadClientType
+	}
+	if client != "" && r.Shows("client") {
+		*fields = append(*fields, text{Type: "mrkdwn", Text: "*Download Client:*\n" + client})
+	}
+
+	b.Blocks = append(b.Blocks, block{Type: "section", Fields: fields})
+
+	if release.ReleaseTitle != "" && r.Shows("release") {
+		b.Blocks = append(b.Blocks, plain(release.ReleaseTitle))
+	}
+
+	return b
 }
 
-func onGrabInfo(c string, d data.Data, ts string) body {
-	b := base(c, d)
+func onDownloadInfo(c string, e *arr.Event, ts string) body {
+	if e.Upgrade {
+		return onUpgradeInfo(c, e, ts)
+	}
+
+	b := base(c, e)
 	b.TS = ts
-	b.Blocks[0].Text.Text = fmt.Sprintf(":large_orange_circle: Grabbed: %s", d.Title())
+	b.Blocks[0].Text.Text = fmt.Sprintf(":large_green_circle: Downloaded: %s", e.Title)
 	b.Blocks = append(b.Blocks,
 		block{
-			Type: "section",
-			Fields: &[]text{
-				{Type: "mrkdwn", Text: "*Quality:*\n" + d.Quality()},
-				{Type: "mrkdwn", Text: "*Release Group:*\n" + d.ReleaseGroup()},
-			},
+			Type:   "section",
+			Fields: releaseFields(e, e.Quality(), e.ReleaseGroup()),
 		},
 	)
 	return b
 }
 
-func onDownloadInfo(c string, d data.Data, ts string) body {
-	b := base(c, d)
+// onUpgradeInfo compares the new download against the files it replaced
+func onUpgradeInfo(c string, e *arr.Event, ts string) body {
+	b := base(c, e)
 	b.TS = ts
-	b.Blocks[0].Text.Text = fmt.Sprintf(":large_green_circle: Downloaded: %s", d.Title())
+	b.Blocks[0].Text.Text = fmt.Sprintf(":arrow_double_up: Upgraded: %s", e.Title)
+
+	quality, group := e.Quality(), e.ReleaseGroup()
+	if len(e.Replaced) > 0 {
+		var qualities, groups []string
+		for _, f := range e.Replaced {
+			if !slices.Contains(qualities, f.Quality) {
+				qualities = append(qualities, f.Quality)
+			}
+			if !slices.Contains(groups, f.ReleaseGroup) {
+				groups = append(groups, f.ReleaseGroup)
+			}
+		}
+		quality = fmt.Sprintf("%s → %s", strings.Join(qualities, ", "), quality)
+		group = fmt.Sprintf("%s → %s", strings.Join(groups, ", "), group)
+	}
+
 	b.Blocks = append(b.Blocks,
 		block{
-			Type: "section",
-			Fields: &[]text{
-				{Type: "mrkdwn", Text: "*Quality:*\n" + d.Quality()},
-				{Type: "mrkdwn", Text: "*Release Group:*\n" + d.ReleaseGroup()},
-			},
+			Type:   "section",
+			Fields: releaseFields(e, quality, group),
 		},
 	)
 	return b
 }
 
-func onAddInfo(c string, d data.Data, ts string) body {
-	b := base(c, d)
+// releaseFields describes a grabbed or downloaded release, with the
+// custom formats it matched when the *arr reports them
+func releaseFields(e *arr.Event, quality string, group string) *[]text {
+	fields := []text{
+		{Type: "mrkdwn", Text: "*Quality:*\n" + quality},
+		{Type: "mrkdwn", Text: "*Release Group:*\n" + group},
+	}
+
+	if cf := e.CustomFormats; cf != nil {
+		names := []string{}
+		for _, f := range cf.CustomFormats {
+			names = append(names, f.Name)
+		}
+		matched := "None"
+		if len(names) > 0 {
+			matched = strings.Join(names, ", ")
+		}
+		fields = append(fields, text{Type: "mrkdwn", Text: fmt.Sprintf("*Custom Formats:*\n%s (score %+d)", matched, cf.CustomFormatScore)})
+	}
+
+	if len(e.Languages) > 0 {
+		fields = append(fields, text{Type: "mrkdwn", Text: "*Languages:*\n" + strings.Join(e.Languages, ", ")})
+	}
+
+	return &fields
+}
+
+// imported adds where a download was moved from and to, when the *arr
+// reports it
+func imported(e *arr.Event, b body) body {
+	if p := e.ImportPaths; p != nil {
+		b.Blocks = append(b.Blocks, plain(fmt.Sprintf("`%s` → `%s`", p.Source, p.Destination)))
+	}
+	return b
+}
+
+func onAddInfo(c string, e *arr.Event, ts string) body {
+	b := base(c, e)
 	b.TS = ts
-	b.Blocks[0].Text.Text = fmt.Sprintf(":large_green_circle: Added: %s", d.Title())
+	b.Blocks[0].Text.Text = fmt.Sprintf(":large_green_circle: Added: %s", e.Title)
 	return b
 }
 
-func onDeleteInfo(c string, d data.Data) body {
-	b := base(c, d)
-	b.Blocks[0].Text.Text = fmt.Sprintf(":red_circle: Delete: %s", d.Title())
+func onDeleteInfo(c string, e *arr.Event) body {
+	b := base(c, e)
+	b.Blocks[0].Text.Text = fmt.Sprintf(":red_circle: Delete: %s", e.Title)
 	return b
 }
 
-func unhandled(c string, d data.Data) body {
-	unhandledData, _ := json.Marshal(d)
+// unhandled shows the webhook as it was sent
+func unhandled(c string, e *arr.Event) body {
 	return body{
 		Channel: c,
 		Blocks: []block{
@@ -241,15 +462,15 @@ func unhandled(c string, d data.Data) body {
 				Type: "section",
 				Text: &text{
 					Type: "plain_text",
-					Text: string(unhandledData),
+					Text: string(e.Raw),
 				},
 			},
 		},
 	}
 }
 
-func base(c string, d data.Data) body {
-	return body{
+func base(c string, e *arr.Event) body {
+	b := body{
 		Channel: c,
 		Blocks: []block{
 			{
@@ -260,16 +481,54 @@ func base(c string, d data.Data) body {
 				Type: "section",
 				Text: &text{
 					Type: "mrkdwn",
-					Text: d.URL(),
+					Text: e.URL,
 				},
 			},
 			{
 				Type: "section",
 				Fields: &[]text{
-					{Type: "mrkdwn", Text: "*Release Date:*\n" + d.ReleaseDate()},
-					{Type: "mrkdwn", Text: "*IMDB:*\nhttps://imdb.com/title/" + d.IMDBID()},
+					{Type: "mrkdwn", Text: "*Release Date:*\n" + e.ReleaseDate},
+					{Type: "mrkdwn", Text: "*IMDB:*\nhttps://imdb.com/title/" + e.Subject.IMDBID},
 				},
 			},
 		},
 	}
+
+	if e.Series != nil {
+		b.Blocks[2].Fields = seriesFields(e, e.Series)
+	}
+
+	return b
+}
+
+// seriesFields describes a series, leaving out anything the *arr didn't send
+func seriesFields(e *arr.Event, s *arr.SeriesInfo) *[]text {
+	fields := []text{}
+	if s.Year != 0 {
+		fields = append(fields, text{Type: "mrkdwn", Text: fmt.Sprintf("*Year:*\n%d", s.Year)})
+	}
+	if s.Network != "" {
+		fields = append(fields, text{Type: "mrkdwn", Text: "*Network:*\n" + s.Network})
+	}
+	if s.Seasons > 0 {
+		fields = append(fields, text{Type: "mrkdwn", Text: fmt.Sprintf("*Seasons:*\n%d", s.Seasons)})
+	}
+	if s.Monitored != nil {
+		monitored := "No"
+		if *s.Monitored {
+			monitored = "Yes"
+		}
+		fields = append(fields, text{Type: "mrkdwn", Text: "*Monitored:*\n" + monitored})
+	}
+	if e.Subject.IMDBID != "" {
+		fields = append(fields, text{Type: "mrkdwn", Text: "*IMDB:*\nhttps://imdb.com/title/" + e.Subject.IMDBID})
+	}
+	if e.Kind == arrhook.EventSeriesDelete {
+		files := "Kept"
+		if s.FilesDeleted {
+			files = "Deleted"
+		}
+		fields = append(fields, text{Type: "mrkdwn", Text: "*Files:*\n" + files})
+	}
+	return &fields
 }
@@ -1,17 +1,26 @@
 package slack
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
-	"github.com/mbarrin/gwarr/internal/pkg/data"
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/internal/pkg/history"
 	"github.com/mbarrin/gwarr/internal/pkg/radarr"
 	"github.com/mbarrin/gwarr/internal/pkg/sonarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
 )
 
 var radarrOnGrab = radarr.Data{
-	Movie: radarr.Movie{
+	Movie: &radarr.Movie{
 		Title:       "Film",
 		Year:        1970,
 		ReleaseDate: "1970-01-01",
@@ -22,7 +31,7 @@ var radarrOnGrab = radarr.Data{
 		Quality:      "1080p",
 		ReleaseGroup: "legit",
 	},
-	EventType:      "Grab",
+	EventType:      arrhook.EventGrab,
 	ApplicationURL: "http://localhost",
 }
 
@@ -93,7 +102,7 @@ var slackRadarrUpdateOnGrab = body{
 }
 
 var radarrOnDownload = radarr.Data{
-	Movie: radarr.Movie{
+	Movie: &radarr.Movie{
 		Title:       "Film",
 		Year:        1970,
 		ReleaseDate: "1970-01-01",
@@ -104,7 +113,7 @@ var radarrOnDownload = radarr.Data{
 		Quality:      "1080p",
 		ReleaseGroup: "legit",
 	},
-	EventType:      "Download",
+	EventType:      arrhook.EventDownload,
 	ApplicationURL: "http://localhost",
 }
 
@@ -142,7 +151,7 @@ var slackRadarrOnDownload = body{
 }
 
 var sonarrOnDownload = sonarr.Data{
-	Series: sonarr.Series{
+	Series: &sonarr.Series{
 		ID:     123,
 		Title:  "Name Of Show!",
 		IMDBID: "tt10574558",
@@ -151,7 +160,7 @@ var sonarrOnDownload = sonarr.Data{
 		{ID: 555, Title: "title", SeasonNumber: 4, EpisodeNumber: 1, AirDate: "1970-01-01"},
 	},
 	EpisodeFile:    &sonarr.EpisodeFile{Quality: "1080p", ReleaseGroup: "legit"},
-	EventType:      "Download",
+	EventType:      arrhook.EventDownload,
 	ApplicationURL: "http://localhost",
 }
 
@@ -191,19 +200,19 @@ var slackSonarrOnDownload = body{
 func TestOnGrabBody(t *testing.T) {
 	tests := map[string]struct {
 		channel  string
-		data     data.Data
+		data     *arr.Event
 		update   bool
 		expected body
 	}{
 		"new movie grab": {
 			channel:  "c123",
-			data:     &radarrOnGrab,
+			data:     radarrOnGrab.Event(),
 			update:   false,
 			expected: slackRadarrOnGrab,
 		},
 		"updated movie grab": {
 			channel:  "c123",
-			data:     &radarrOnGrab,
+			data:     radarrOnGrab.Event(),
 			update:   true,
 			expected: slackRadarrUpdateOnGrab,
 		},
@@ -214,7 +223,7 @@ func TestOnGrabBody(t *testing.T) {
 		if tc.update {
 			ts = "1234"
 		}
-		actual := onGrabInfo(tc.channel, tc.data, ts)
+		actual := onGrabInfo(config.Route{Channel: tc.channel}, tc.data, ts)
 		assert.Equal(t, tc.expected, actual)
 	}
 }
@@ -222,19 +231,19 @@ func TestOnGrabBody(t *testing.T) {
 func TestOnDownloadBody(t *testing.T) {
 	tests := map[string]struct {
 		channel  string
-		data     data.Data
+		data     *arr.Event
 		ts       string
 		expected body
 	}{
 		"new movie download": {
 			channel:  "c123",
-			data:     &radarrOnDownload,
+			data:     radarrOnDownload.Event(),
 			ts:       "123",
 			expected: slackRadarrOnDownload,
 		},
 		"new episode download": {
 			channel:  "c123",
-			data:     &sonarrOnDownload,
+			data:     sonarrOnDownload.Event(),
 			ts:       "123",
 			expected: slackSonarrOnDownload,
 		},
@@ -245,3 +254,342 @@ func TestOnDownloadBody(t *testing.T) {
 		assert.Equal(t, tc.expected, actual)
 	}
 }
+
+var radarrOnRename = radarr.Data{
+	Movie: &radarr.Movie{
+		Title:       "Film",
+		Year:        1970,
+		ReleaseDate: "1970-01-01",
+		IMDBID:      "tt8415836",
+		TMDBID:      55,
+	},
+	RenamedMovieFiles: []radarr.RenamedMovieFiles{
+		{PreviousRelativePath: "film.mkv", RelativePath: "Film (1970).mkv"},
+		{PreviousRelativePath: "film.srt", RelativePath: "Film (1970).en.srt"},
+	},
+	EventType:      arrhook.EventRename,
+	ApplicationURL: "http://localhost",
+}
+
+func TestOnRenameBody(t *testing.T) {
+	actual := onRenameInfo("c123", radarrOnRename.Event())
+
+	assert.Equal(t, ":pencil2: Renamed: Film (1970)", actual.Blocks[0].Text.Text)
+	assert.Equal(t, block{
+		Type: "section",
+		Text: &text{Type: "mrkdwn", Text: "`film.mkv` → `Film (1970).mkv`\n`film.srt` → `Film (1970).en.srt`"},
+	}, actual.Blocks[3])
+}
+
+func TestOnRenameBodyCollapsed(t *testing.T) {
+	many := radarrOnRename
+	many.RenamedMovieFiles = nil
+	for i := 0; i < 8; i++ {
+		many.RenamedMovieFiles = append(many.RenamedMovieFiles, radarr.RenamedMovieFiles{PreviousRelativePath: "a", RelativePath: "b"})
+	}
+
+	actual := onRenameInfo("c123", many.Event())
+	assert.Equal(t, strings.Repeat("`a` → `b`\n", 5)+"…and 3 more", actual.Blocks[3].Text.Text)
+}
+
+func TestRenameSummary(t *testing.T) {
+	movie := *radarrOnRename.Movie
+	movie.Title, movie.TMDBID = "Other Film", 56
+	other := radarrOnRename
+	other.Movie = &movie
+	other.RenamedMovieFiles = other.RenamedMovieFiles[:1]
+
+	expected := body{
+		Channel: "c123",
+		Text:    "Renamed 3 files across 2 movies",
+		Blocks: []block{
+			header(":pencil2: Renamed 3 files across 2 movies"),
+			list("Renamed", []string{
+				"<http://localhost/movie/55|Film (1970)> (2 files)",
+				"<http://localhost/movie/56|Other Film (1970)> (1 file)",
+			}),
+		},
+	}
+
+	assert.Equal(t, expected, renameSummary("c123", []*arr.Event{radarrOnRename.Event(), other.Event()}))
+}
+
+func TestOnUpgradeBody(t *testing.T) {
+	upgrade := radarrOnDownload
+	upgrade.IsUpgrade = true
+	upgrade.MovieFile = &radarr.MovieFile{Quality: "Bluray-2160p", ReleaseGroup: "new"}
+	upgrade.DeletedFiles = radarr.DeletedFiles{Files: []radarr.MovieFile{{Quality: "WEBDL-720p", ReleaseGroup: "old"}}}
+
+	actual := onDownloadInfo("c123", upgrade.Event(), "123")
+	assert.Equal(t, ":arrow_double_up: Upgraded: Film (1970)", actual.Blocks[0].Text.Text)
+	assert.Equal(t, &[]text{
+		{Type: "mrkdwn", Text: "*Quality:*\nWEBDL-720p → Bluray-2160p"},
+		{Type: "mrkdwn", Text: "*Release Group:*\nold → new"},
+	}, actual.Blocks[3].Fields)
+
+	upgrade.DeletedFiles = radarr.DeletedFiles{}
+	actual = onDownloadInfo("c123", upgrade.Event(), "123")
+	assert.Equal(t, &[]text{
+		{Type: "mrkdwn", Text: "*Quality:*\nBluray-2160p"},
+		{Type: "mrkdwn", Text: "*Release Group:*\nnew"},
+	}, actual.Blocks[3].Fields)
+}
+
+func TestReleaseFieldsCustomFormats(t *testing.T) {
+	grab := radarrOnGrab
+	grab.CustomFormatInfo = &radarr.CustomFormatInfo{
+		CustomFormats:     []radarr.CustomFormat{{ID: 1, Name: "HDR"}, {ID: 2, Name: "x265"}},
+		CustomFormatScore: 25,
+	}
+
+	actual := onGrabInfo(config.Route{Channel: "c123"}, grab.Event(), "")
+	assert.Equal(t, text{Type: "mrkdwn", Text: "*Custom Formats:*\nHDR, x265 (score +25)"}, (*actual.Blocks[3].Fields)[2])
+	assert.Equal(t, 25, *grab.Event().Score())
+
+	grab.CustomFormatInfo = &radarr.CustomFormatInfo{CustomFormatScore: -10}
+	actual = onGrabInfo(config.Route{Channel: "c123"}, grab.Event(), "")
+	assert.Equal(t, text{Type: "mrkdwn", Text: "*Custom Formats:*\nNone (score -10)"}, (*actual.Blocks[3].Fields)[2])
+
+	assert.Nil(t, radarrOnGrab.Event().Score())
+}
+
+func TestV4Download(t *testing.T) {
+	download := sonarrOnDownload
+	download.EpisodeFile = &sonarr.EpisodeFile{
+		Quality:      "1080p",
+		ReleaseGroup: "legit",
+		Path:         "/tv/Show/Season 4/01.mkv",
+		SourcePath:   "/downloads/show/01.mkv",
+		Languages:    []sonarr.Language{{ID: 1, Name: "English"}, {ID: 8, Name: "Japanese"}},
+	}
+
+	actual := message(config.Route{Channel: "c123"}, download.Event(), "")
+	assert.Equal(t, text{Type: "mrkdwn", Text: "*Languages:*\nEnglish, Japanese"}, (*actual.Blocks[3].Fields)[2])
+	assert.Equal(t, plain("`/downloads/show/01.mkv` → `/tv/Show/Season 4/01.mkv`"), actual.Blocks[4])
+
+	assert.Len(t, message(config.Route{Channel: "c123"}, sonarrOnDownload.Event(), "").Blocks, 4)
+}
+
+func TestOnGrabBodyDetails(t *testing.T) {
+	grab := radarrOnGrab
+	grab.Release = &radarr.Release{
+		Quality:      "1080p",
+		ReleaseGroup: "legit",
+		ReleaseTitle: "Film.1970.1080p.BluRay.x264-legit",
+		Indexer:      "NZBgeek",
+		Size:         3 << 29,
+	}
+	grab.DownloadClient = "SABnzbd"
+	grab.DownloadClientType = "Sabnzbd"
+
+	actual := onGrabInfo(config.Route{Channel: "c123"}, grab.Event(), "")
+	assert.Equal(t, &[]text{
+		{Type: "mrkdwn", Text: "*Quality:*\n1080p"},
+		{Type: "mrkdwn", Text: "*Release Group:*\nlegit"},
+		{Type: "mrkdwn", Text: "*Size:*\n1.50 GiB"},
+		{Type: "mrkdwn", Text: "*Indexer:*\nNZBgeek"},
+		{Type: "mrkdwn", Text: "*Download Client:*\nSABnzbd"},
+	}, actual.Blocks[3].Fields)
+	assert.Equal(t, plain("Film.1970.1080p.BluRay.x264-legit"), actual.Blocks[4])
+
+	hidden := config.Route{Channel: "c123", HideFields: []string{"size", "client", "release"}}
+	actual = onGrabInfo(hidden, grab.Event(), "")
+	assert.Equal(t, &[]text{
+		{Type: "mrkdwn", Text: "*Quality:*\n1080p"},
+		{Type: "mrkdwn", Text: "*Release Group:*\nlegit"},
+		{Type: "mrkdwn", Text: "*Indexer:*\nNZBgeek"},
+	}, actual.Blocks[3].Fields)
+	assert.Len(t, actual.Blocks, 4)
+}
+
+func TestLinked(t *testing.T) {
+	route := config.Route{Channel: "c123", DeepLinks: true}
+
+	actual := message(route, sonarrOnDownload.Event(), "")
+	assert.Equal(t, sonarrOnDownload.DeepURL(), actual.Blocks[1].Text.Text)
+
+	actual = message(config.Route{Channel: "c123"}, sonarrOnDownload.Event(), "")
+	assert.Equal(t, sonarrOnDownload.URL(), actual.Blocks[1].Text.Text)
+
+	actual = message(route, radarrOnDownload.Event(), "")
+	assert.Equal(t, radarrOnDownload.URL(), actual.Blocks[1].Text.Text)
+}
+
+var monitored = true
+
+var sonarrOnSeriesAdd = sonarr.Data{
+	Series: &sonarr.Series{
+		ID:        12,
+		Title:     "Show",
+		TitleSlug: "show",
+		IMDBID:    "tt0000012",
+		Year:      2016,
+		Network:   "HBO",
+		Monitored: &monitored,
+		Seasons:   []sonarr.Season{{SeasonNumber: 0}, {SeasonNumber: 1}, {SeasonNumber: 2}},
+	},
+	EventType:      arrhook.EventSeriesAdd,
+	ApplicationURL: "http://localhost",
+}
+
+func TestOnSeriesAddInfo(t *testing.T) {
+	expected := body{
+		Channel: "c123",
+		Blocks: []block{
+			header(":large_green_circle: Added: Show"),
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: "http://localhost/series/show"}},
+			{
+				Type: "section",
+				Fields: &[]text{
+					{Type: "mrkdwn", Text: "*Year:*\n2016"},
+					{Type: "mrkdwn", Text: "*Network:*\nHBO"},
+					{Type: "mrkdwn", Text: "*Seasons:*\n2"},
+					{Type: "mrkdwn", Text: "*Monitored:*\nYes"},
+					{Type: "mrkdwn", Text: "*IMDB:*\nhttps://imdb.com/title/tt0000012"},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, expected, message(config.Route{Channel: "c123"}, sonarrOnSeriesAdd.Event(), ""))
+}
+
+func TestSeriesDeleteMessage(t *testing.T) {
+	deleted := sonarr.Data{
+		Series:         &sonarr.Series{ID: 12, Title: "Show", TitleSlug: "show"},
+		DeletedFiles:   sonarr.DeletedFiles{Deleted: true},
+		EventType:      arrhook.EventSeriesDelete,
+		ApplicationURL: "http://localhost",
+	}
+
+	method, b := deleteMessage(config.Route{Channel: "c123", OnDelete: "strike"}, deleted.Event(), "1234")
+	assert.Equal(t, "chat.update", method)
+	assert.Equal(t, ":wastebasket: Removed: Show", b.Blocks[0].Text.Text)
+	assert.Equal(t, &[]text{{Type: "mrkdwn", Text: "*Files:*\nDeleted"}}, b.Blocks[2].Fields)
+}
+
+var sonarrOnRename = sonarr.Data{
+	Series: &sonarr.Series{ID: 12, Title: "Show", TitleSlug: "show"},
+	RenamedEpisodeFiles: []sonarr.RenamedEpisodeFiles{
+		{PreviousRelativePath: "Season 1/show.s01e01.mkv", RelativePath: "Season 1/Show - S01E01 - Pilot.mkv"},
+	},
+	EventType:      arrhook.EventRename,
+	ApplicationURL: "http://localhost",
+}
+
+func TestGroupRenames(t *testing.T) {
+	more := sonarrOnRename
+	more.RenamedEpisodeFiles = []sonarr.RenamedEpisodeFiles{
+		{PreviousRelativePath: "Season 2/show.s02e01.mkv", RelativePath: "Season 2/Show - S02E01 - Return.mkv"},
+	}
+
+	grouped := groupRenames([]*arr.Event{sonarrOnRename.Event(), radarrOnRename.Event(), more.Event()})
+	assert.Len(t, grouped, 2)
+
+	actual := onRenameInfo("c123", grouped[0])
+	assert.Equal(t, ":pencil2: Renamed: Show", actual.Blocks[0].Text.Text)
+	assert.Equal(t, "`Season 1/show.s01e01.mkv` → `Season 1/Show - S01E01 - Pilot.mkv`\n"+
+		"`Season 2/show.s02e01.mkv` → `Season 2/Show - S02E01 - Return.mkv`", actual.Blocks[3].Text.Text)
+
+	other := sonarrOnRename
+	other.Series = &sonarr.Series{ID: 13, Title: "Other Show", TitleSlug: "other-show"}
+	summary := renameSummary("c123", groupRenames([]*arr.Event{sonarrOnRename.Event(), more.Event(), other.Event()}))
+	assert.Equal(t, "Renamed 3 files across 2 series", summary.Text)
+}
+
+func TestFlushRenames(t *testing.T) {
+	posted := map[string]body{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b body
+		_ = json.NewDecoder(r.Body).Decode(&b)
+		posted[b.Channel] = b
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	series := *sonarrOnRename.Series
+	series.ID, series.Title, series.Tags = 13, "Kids Show", []string{"kids"}
+	kids := sonarrOnRename
+	kids.Series = &series
+
+	sc := Client{url: server.URL + "/", client: *server.Client(), renames: newRenameQueue(), routes: []config.Route{
+		{Name: "all", Channel: "all"},
+		{Name: "kids", Channel: "kids", Tags: []string{"kids"}},
+		{Name: "4k", Channel: "4k", Tags: []string{"4k"}},
+	}}
+	sc.renames.pending["sonarr"] = []*arr.Event{sonarrOnRename.Event(), kids.Event()}
+	sc.flushRenames("sonarr")
+
+	assert.Len(t, posted, 2, "routes without a tagged rename get nothing")
+	assert.Equal(t, "Renamed 2 files across 2 series", posted["all"].Text)
+	assert.Equal(t, ":pencil2: Renamed: Kids Show", posted["kids"].Blocks[0].Text.Text)
+}
+
+// slackCall is a request made to the stub Slack API
+type slackCall struct {
+	Method string
+	Body   body
+}
+
+// slackStub records the requests made to a stub Slack API, answering each
+// with reply, or a successful post when reply is nil
+type slackStub struct {
+	mu    sync.Mutex
+	calls []slackCall
+	reply func(method string, b body) string
+}
+
+func (s *slackStub) Calls() []slackCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.calls)
+}
+
+// filterCalls returns the calls made to a Slack method
+func filterCalls(calls []slackCall, method string) []slackCall {
+	var out []slackCall
+	for _, c := range calls {
+		if c.Method == method {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// newStubClient returns a client for the routes backed by a stub Slack API
+// and a fake Redis
+func newStubClient(t *testing.T, routes []config.Route) (*Client, *slackStub) {
+	t.Helper()
+
+	stub := &slackStub{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b body
+		_ = json.NewDecoder(r.Body).Decode(&b)
+		method := strings.TrimPrefix(r.URL.Path, "/")
+
+		stub.mu.Lock()
+		stub.calls = append(stub.calls, slackCall{Method: method, Body: b})
+		reply := stub.reply
+		stub.mu.Unlock()
+
+		if reply == nil {
+			_, _ = w.Write([]byte(`{"ok": true, "ts": "5678"}`))
+			return
+		}
+		_, _ = w.Write([]byte(reply(method, b)))
+	}))
+	t.Cleanup(server.Close)
+
+	rc := newTestRedis(t)
+	sc := &Client{
+		url:     server.URL + "/",
+		client:  *server.Client(),
+		routes:  routes,
+		redis:   rc,
+		history: history.New(rc, historySize),
+		renames: newRenameQueue(),
+		locks:   newKeyLocks(),
+		checks:  newAiringChecks(),
+	}
+	return sc, stub
+}
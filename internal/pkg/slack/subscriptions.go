@@ -0,0 +1,78 @@
+package slack
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+)
+
+func subscriptionsKey(user string) string { return "subscriptions:" + user }
+func subscribersKey(item string) string   { return "subscribers:" + item }
+func itemKey(e *arr.Event) string         { return fmt.Sprintf("%s:%d", e.Source, e.ID) }
+
+func (sc *Client) subscribe(user string, item string) error {
+	pipe := sc.redis.TxPipeline()
+	pipe.SAdd(ctx, subscriptionsKey(user), item)
+	pipe.SAdd(ctx, subscribersKey(item), user)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (sc *Client) unsubscribe(user string, item string) error {
+	pipe := sc.redis.TxPipeline()
+	pipe.SRem(ctx, subscriptionsKey(user), item)
+	pipe.SRem(ctx, subscribersKey(item), user)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (sc *Client) subscriptions(user string) ([]string, error) {
+	return sc.redis.SMembers(ctx, subscriptionsKey(user)).Result()
+}
+
+// notifySubscribers replies to a download message mentioning everyone
+// subscribed to the item, then clears their subscriptions
+func (sc *Client) notifySubscribers(channel string, e *arr.Event, ts string) {
+	item := itemKey(e)
+
+	users, err := sc.redis.SMembers(ctx, subscribersKey(item)).Result()
+	if err != nil {
+		slog.With("package", "slack").Error(err.Error())
+		return
+	}
+
+	if len(users) == 0 {
+		return
+	}
+
+	response, err := sc.call("chat.postMessage", subscribersReply(channel, e, ts, users))
+	if err != nil {
+		slog.With("package", "slack").Error(err.Error())
+		return
+	}
+
+	if !response.OK {
+		slog.With("package", "slack").Error(response.Error)
+		return
+	}
+
+	for _, u := range users {
+		if err := sc.unsubscribe(u, item); err != nil {
+			slog.With("package", "slack").Error(err.Error())
+		}
+	}
+}
+
+func subscribersReply(c string, e *arr.Event, ts string, users []string) body {
+	mentions := ""
+	for _, u := range users {
+		mentions += fmt.Sprintf("<@%s> ", u)
+	}
+
+	return body{
+		Channel:  c,
+		ThreadTS: ts,
+		Text:     fmt.Sprintf("%s%s has downloaded", mentions, e.Title),
+	}
+}
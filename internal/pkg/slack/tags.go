@@ -0,0 +1,48 @@
+package slack
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+)
+
+// tagged adds an item's tags to a message, and mentions whoever the
+// route asks to be told about those tags
+func tagged(r config.Route, e *arr.Event, b body) body {
+	tags := e.Subject.Tags
+	if len(tags) == 0 {
+		return b
+	}
+
+	labels := []string{}
+	mentions := []string{}
+	for _, t := range tags {
+		labels = append(labels, "`"+t+"`")
+		if id, ok := r.Mentions[t]; ok {
+			m := mention(id)
+			if !slices.Contains(mentions, m) {
+				mentions = append(mentions, m)
+			}
+		}
+	}
+
+	b.Blocks = append(b.Blocks, plain("Tags: "+strings.Join(labels, " ")))
+	if len(mentions) > 0 {
+		b.Blocks = append(b.Blocks, block{
+			Type: "section",
+			Text: &text{Type: "mrkdwn", Text: "cc " + strings.Join(mentions, " ")},
+		})
+	}
+
+	return b
+}
+
+// mention formats a Slack user or user group ID so it notifies them
+func mention(id string) string {
+	if strings.HasPrefix(id, "S") {
+		return "<!subteam^" + id + ">"
+	}
+	return "<@" + id + ">"
+}
@@ -0,0 +1,43 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/internal/pkg/radarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+func TestTagged(t *testing.T) {
+	movie := *radarrOnGrab.Movie
+	movie.Tags = arr.Tags{"kids", "4k", "requested-by-alice"}
+	grab := radarrOnGrab
+	grab.Movie = &movie
+
+	route := config.Route{
+		Channel: "c123",
+		Mentions: map[string]string{
+			"kids":               "S0PARENTS",
+			"requested-by-alice": "U0ALICE",
+			"anime":              "U0BOB",
+		},
+	}
+
+	actual := message(route, grab.Event(), "")
+	assert.Equal(t, []block{
+		plain("Tags: `kids` `4k` `requested-by-alice`"),
+		{Type: "section", Text: &text{Type: "mrkdwn", Text: "cc <!subteam^S0PARENTS> <@U0ALICE>"}},
+	}, actual.Blocks[len(actual.Blocks)-2:])
+
+	assert.Equal(t, onGrabInfo(route, radarrOnGrab.Event(), ""), message(route, radarrOnGrab.Event(), ""))
+}
+
+func TestTaggedNoMentions(t *testing.T) {
+	download := radarr.Data{Movie: &radarr.Movie{Title: "Film", Tags: arr.Tags{"4k"}}, EventType: arrhook.EventDownload, MovieFile: &radarr.MovieFile{}}
+
+	actual := message(config.Route{Channel: "c123"}, download.Event(), "")
+	assert.Equal(t, plain("Tags: `4k`"), actual.Blocks[len(actual.Blocks)-1])
+}
@@ -0,0 +1,272 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+// parent defines the long-lived message for a series on a route, which
+// each episode's messages are threaded under
+type parent struct {
+	TS     string `json:"ts"`
+	Latest string `json:"latest,omitempty"`
+	// Episodes holds the last lifecycle state of each episode, like
+	// "S02E05": "Download"
+	Episodes map[string]arrhook.EventKind `json:"episodes,omitempty"`
+	// Next is when the series' next episode airs, if Sonarr knows
+	Next *time.Time `json:"next,omitempty"`
+}
+
+// AiringSource reports when the next episode of a series airs, or the
+// zero time if none is scheduled
+type AiringSource interface {
+	NextAiring(seriesID int) (time.Time, error)
+}
+
+// airingTTL is how long a series' next airing is shown before Sonarr is
+// asked again
+const airingTTL = time.Hour
+
+// airingChecks holds when the next airing shown on each parent message
+// was last looked up
+type airingChecks struct {
+	mu      sync.Mutex
+	checked map[string]time.Time
+}
+
+func newAiringChecks() *airingChecks {
+	return &airingChecks{checked: map[string]time.Time{}}
+}
+
+// due returns true if a parent's next airing should be looked up again,
+// and marks it as looked up. Lookups older than airingTTL are forgotten
+func (ac *airingChecks) due(key string, now time.Time) bool {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if checked, ok := ac.checked[key]; ok && now.Sub(checked) < airingTTL {
+		return false
+	}
+
+	for k, checked := range ac.checked {
+		if now.Sub(checked) >= airingTTL {
+			delete(ac.checked, k)
+		}
+	}
+	ac.checked[key] = now
+	return true
+}
+
+// ShowAirings asks a Sonarr instance when each series' next episode airs,
+// so it can be shown on the series' parent message
+func (sc *Client) ShowAirings(a AiringSource) {
+	sc.airing = a
+}
+
+// threadKey returns the cache hash holding the parent message of each
+// series on a route
+func threadKey(r config.Route, e *arr.Event) string {
+	return "threads:" + e.Source + ":" + r.Name
+}
+
+// parentKey returns the key a series' parent message on a route is
+// locked under
+func parentKey(r config.Route, e *arr.Event) string {
+	return fmt.Sprintf("%s:%d", threadKey(r, e), e.Subject.ID)
+}
+
+// parentWait is how long a webhook waits for a parent message another
+// webhook is posting, before posting its own message unthreaded
+const parentWait = 2 * time.Second
+
+// parent returns the parent message for the series an episode belongs
+// to, posting it if there isn't one yet. Anything that isn't about an
+// episode has no parent
+func (sc *Client) parent(r config.Route, e *arr.Event) *parent {
+	if len(e.Subject.Episodes) == 0 {
+		return nil
+	}
+	field := fmt.Sprint(e.Subject.ID)
+
+	if p := sc.loadParent(r, e, field); p != nil {
+		return p
+	}
+
+	// Episodes of a new series often arrive together, so the parent is
+	// posted under the series' lock and the rest wait for it. One that
+	// waits too long is posted unthreaded rather than held up
+	unlock, ok := sc.locks.lockWithin(parentKey(r, e), parentWait)
+	if !ok {
+		return nil
+	}
+	defer unlock()
+
+	// It may have been posted while waiting
+	if p := sc.loadParent(r, e, field); p != nil {
+		return p
+	}
+
+	p := &parent{Episodes: map[string]arrhook.EventKind{}}
+	response, err := sc.call("chat.postMessage", onParentInfo(r.Channel, e, p))
+	if err != nil {
+		slog.With("package", "slack").Error(err.Error())
+		return nil
+	}
+	if !response.OK {
+		slog.Error(response.Error)
+		return nil
+	}
+
+	p.TS = response.TS
+	sc.saveParent(r, e, p)
+	return p
+}
+
+func (sc *Client) loadParent(r config.Route, e *arr.Event, field string) *parent {
+	raw, err := sc.redis.HGet(ctx, threadKey(r, e), field).Result()
+	if err != nil {
+		return nil
+	}
+
+	p := parent{}
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		slog.Error(err.Error())
+		return nil
+	}
+	return &p
+}
+
+// updateParent records an episode's new state on its series' parent. The
+// next airing shown is looked up after, so Sonarr doesn't hold it up
+func (sc *Client) updateParent(r config.Route, e *arr.Event, p *parent) {
+	unlock := sc.locks.lock(parentKey(r, e))
+	defer unlock()
+
+	// Other episodes may have updated it since it was loaded
+	if current := sc.loadParent(r, e, fmt.Sprint(e.Subject.ID)); current != nil {
+		p = current
+	}
+	if p.Episodes == nil {
+		p.Episodes = map[string]arrhook.EventKind{}
+	}
+
+	labels := []string{}
+	for _, ep := range e.Subject.Episodes {
+		label := fmt.Sprintf("S%02dE%02d", ep.SeasonNumber, ep.EpisodeNumber)
+		p.Episodes[label] = e.Kind
+		labels = append(labels, label)
+	}
+	p.Latest = fmt.Sprintf("%s %s", strings.Join(labels, ", "), strings.ToLower(lifecycle[e.Kind].label))
+
+	sc.editParent(r, e, p)
+
+	if sc.airing != nil && sc.checks.due(parentKey(r, e), time.Now()) {
+		go sc.refreshAiring(r, e)
+	}
+}
+
+// editParent updates a parent message and saves it
+func (sc *Client) editParent(r config.Route, e *arr.Event, p *parent) {
+	response, err := sc.call("chat.update", onParentInfo(r.Channel, e, p))
+	if err != nil {
+		slog.With("package", "slack").Error(err.Error())
+	} else if !response.OK {
+		slog.Error(response.Error)
+	}
+
+	sc.saveParent(r, e, p)
+}
+
+// refreshAiring looks up when the next episode of a series airs and shows
+// it on the series' parent message. The last known time is kept if Sonarr
+// can't be reached
+func (sc *Client) refreshAiring(r config.Route, e *arr.Event) {
+	next, err := sc.airing.NextAiring(e.Subject.ID)
+	if err != nil {
+		slog.With("package", "slack").Error(err.Error())
+		return
+	}
+
+	unlock := sc.locks.lock(parentKey(r, e))
+	defer unlock()
+
+	p := sc.loadParent(r, e, fmt.Sprint(e.Subject.ID))
+	if p == nil {
+		return
+	}
+
+	p.Next = nil
+	if !next.IsZero() {
+		p.Next = &next
+	}
+	sc.editParent(r, e, p)
+}
+
+func (sc *Client) saveParent(r config.Route, e *arr.Event, p *parent) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	err = sc.redis.HSet(ctx, threadKey(r, e), fmt.Sprint(e.Subject.ID), b).Err()
+	if err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// forgetParent removes the parent message of a deleted series, so it
+// starts afresh if the series is added again
+func (sc *Client) forgetParent(r config.Route, e *arr.Event) {
+	err := sc.redis.HDel(ctx, threadKey(r, e), fmt.Sprint(e.Subject.ID)).Err()
+	if err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func onParentInfo(c string, e *arr.Event, p *parent) body {
+	title := e.Subject.Title
+
+	counts := map[arrhook.EventKind]int{}
+	for _, state := range p.Episodes {
+		counts[state]++
+	}
+
+	status := []string{}
+	if p.Latest != "" {
+		status = append(status, "Latest: "+p.Latest)
+	}
+	if n := counts[arrhook.EventDownload]; n > 0 {
+		status = append(status, plural(n, "episode")+" downloaded")
+	}
+	if n := counts[arrhook.EventGrab]; n > 0 {
+		status = append(status, plural(n, "episode")+" grabbed")
+	}
+	if p.Next != nil {
+		status = append(status, "Next airing "+p.Next.Local().Format("Jan 2 15:04"))
+	}
+
+	b := body{
+		Channel: c,
+		TS:      p.TS,
+		Text:    title,
+		Blocks: []block{
+			header(":tv: " + title),
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: e.URL}},
+		},
+	}
+
+	if len(status) > 0 {
+		b.Blocks = append(b.Blocks, plain(strings.Join(status, " · ")))
+	}
+
+	return b
+}
@@ -0,0 +1,139 @@
+package slack
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnParentInfo(t *testing.T) {
+	p := &parent{
+		TS:     "1234",
+		Latest: "S02E05 downloaded",
+		Episodes: map[string]arrhook.EventKind{
+			"S02E03": arrhook.EventDownload,
+			"S02E04": arrhook.EventDownload,
+			"S02E05": arrhook.EventDownload,
+			"S02E06": arrhook.EventGrab,
+		},
+	}
+
+	expected := body{
+		Channel: "c123",
+		TS:      "1234",
+		Text:    "Name Of Show!",
+		Blocks: []block{
+			header(":tv: Name Of Show!"),
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: sonarrOnDownload.URL()}},
+			plain("Latest: S02E05 downloaded · 3 episodes downloaded · 1 episode grabbed"),
+		},
+	}
+
+	assert.Equal(t, expected, onParentInfo("c123", sonarrOnDownload.Event(), p))
+
+	next := time.Date(2026, 10, 20, 21, 0, 0, 0, time.Local)
+	p.Next = &next
+	airing := onParentInfo("c123", sonarrOnDownload.Event(), p)
+	assert.Equal(t, plain("Latest: S02E05 downloaded · 3 episodes downloaded · 1 episode grabbed · Next airing Oct 20 21:00"), airing.Blocks[2])
+
+	fresh := onParentInfo("c123", sonarrOnDownload.Event(), &parent{})
+	assert.Len(t, fresh.Blocks, 2)
+}
+
+func TestAiringChecks(t *testing.T) {
+	now := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+	ac := newAiringChecks()
+
+	assert.True(t, ac.due("threads:sonarr:tv:12", now))
+	assert.False(t, ac.due("threads:sonarr:tv:12", now.Add(30*time.Minute)), "looked up recently")
+	assert.True(t, ac.due("threads:sonarr:tv:13", now.Add(30*time.Minute)), "series are looked up on their own")
+	assert.True(t, ac.due("threads:sonarr:tv:12", now.Add(time.Hour)))
+
+	ac.due("threads:sonarr:tv:14", now.Add(3*time.Hour))
+	assert.Len(t, ac.checked, 1, "old lookups are forgotten")
+}
+
+func TestParentClaim(t *testing.T) {
+	r := config.Route{Name: "tv", Channel: "c123", ThreadSeries: true}
+	sc, stub := newStubClient(t, []config.Route{r})
+	stub.reply = func(string, body) string {
+		// Hold the parent's lock long enough for the others to queue
+		time.Sleep(50 * time.Millisecond)
+		return `{"ok": true, "ts": "5678"}`
+	}
+
+	var wg sync.WaitGroup
+	parents := make([]*parent, 5)
+	for i := range parents {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			parents[i] = sc.parent(r, sonarrOnDownload.Event())
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, stub.Calls(), 1, "the parent is posted once")
+	for _, p := range parents {
+		if assert.NotNil(t, p) {
+			assert.Equal(t, "5678", p.TS, "the others wait for it")
+		}
+	}
+}
+
+func TestParentWait(t *testing.T) {
+	r := config.Route{Name: "tv", Channel: "c123", ThreadSeries: true}
+	sc, stub := newStubClient(t, []config.Route{r})
+
+	unlock := sc.locks.lock(parentKey(r, sonarrOnDownload.Event()))
+	defer unlock()
+
+	start := time.Now()
+	assert.Nil(t, sc.parent(r, sonarrOnDownload.Event()), "a held parent is given up on")
+	assert.GreaterOrEqual(t, time.Since(start), parentWait)
+	assert.Empty(t, stub.Calls())
+}
+
+// fakeAiring is an AiringSource that answers once released
+type fakeAiring struct {
+	next    time.Time
+	release chan struct{}
+}
+
+func (f *fakeAiring) NextAiring(int) (time.Time, error) {
+	<-f.release
+	return f.next, nil
+}
+
+func TestUpdateParentAiring(t *testing.T) {
+	r := config.Route{Name: "tv", Channel: "c123", ThreadSeries: true}
+	sc, stub := newStubClient(t, []config.Route{r})
+
+	next := time.Date(2026, 10, 20, 21, 0, 0, 0, time.Local)
+	airing := &fakeAiring{next: next, release: make(chan struct{})}
+	sc.ShowAirings(airing)
+
+	e := sonarrOnDownload.Event()
+	p := sc.parent(r, e)
+	sc.updateParent(r, e, p)
+
+	updates := filterCalls(stub.Calls(), "chat.update")
+	assert.Len(t, updates, 1, "the update doesn't wait on Sonarr")
+	assert.Len(t, updates[0].Body.Blocks, 3)
+
+	close(airing.release)
+	assert.Eventually(t, func() bool { return len(filterCalls(stub.Calls(), "chat.update")) == 2 }, time.Second, 10*time.Millisecond)
+
+	refreshed := filterCalls(stub.Calls(), "chat.update")[1]
+	assert.Contains(t, refreshed.Body.Blocks[2].Elements[0].Text, "Next airing Oct 20 21:00")
+	assert.True(t, next.Equal(*sc.loadParent(r, e, fmt.Sprint(e.Subject.ID)).Next), "the airing is saved")
+
+	sc.updateParent(r, e, p)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, filterCalls(stub.Calls(), "chat.update"), 3, "the airing isn't looked up again within the hour")
+}
@@ -0,0 +1,49 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+)
+
+func onUpdateInfo(c string, e *arr.Event) body {
+	update := e.Update
+	if update == nil {
+		return unhandled(c, e)
+	}
+
+	summary := fmt.Sprintf("%s %s → %s", service(e), shortVersion(update.PreviousVersion), shortVersion(update.NewVersion))
+	notes := fmt.Sprintf("https://github.com/%[1]s/%[1]s/releases/tag/v%[2]s", service(e), update.NewVersion)
+
+	b := body{
+		Channel: c,
+		Text:    summary,
+		Blocks: []block{
+			header(":arrow_up: " + summary),
+		},
+	}
+
+	if update.Message != "" {
+		b.Blocks = append(b.Blocks, block{
+			Type: "section",
+			Text: &text{Type: "mrkdwn", Text: update.Message},
+		})
+	}
+
+	b.Blocks = append(b.Blocks, block{
+		Type:     "context",
+		Elements: []text{{Type: "mrkdwn", Text: fmt.Sprintf("<%s|Release notes for %s>", notes, update.NewVersion)}},
+	})
+
+	return b
+}
+
+// shortVersion drops the build number from a version like 5.2.0.8270
+func shortVersion(v string) string {
+	parts := strings.Split(v, ".")
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,31 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/sonarr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+var sonarrOnUpdate = sonarr.Data{
+	EventType:       arrhook.EventApplicationUpdate,
+	PreviousVersion: "4.0.0.700",
+	NewVersion:      "4.0.1.929",
+	Message:         "Sonarr updated from 4.0.0.700 to 4.0.1.929",
+}
+
+func TestOnUpdateInfo(t *testing.T) {
+	expected := body{
+		Channel: "c123",
+		Text:    "Sonarr 4.0.0 → 4.0.1",
+		Blocks: []block{
+			header(":arrow_up: Sonarr 4.0.0 → 4.0.1"),
+			{Type: "section", Text: &text{Type: "mrkdwn", Text: "Sonarr updated from 4.0.0.700 to 4.0.1.929"}},
+			{Type: "context", Elements: []text{{Type: "mrkdwn", Text: "<https://github.com/Sonarr/Sonarr/releases/tag/v4.0.1.929|Release notes for 4.0.1.929>"}}},
+		},
+	}
+
+	assert.Equal(t, expected, onUpdateInfo("c123", sonarrOnUpdate.Event()))
+}
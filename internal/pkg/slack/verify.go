@@ -0,0 +1,47 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRequestAge is how old a signed Slack request can be before it is rejected
+const maxRequestAge = 5 * time.Minute
+
+// Verify checks that a request was signed by Slack with the signing secret
+func (sc *Client) Verify(h http.Header, b []byte) error {
+	return verify(sc.secret, h, b, time.Now())
+}
+
+func verify(secret string, h http.Header, b []byte, now time.Time) error {
+	if secret == "" {
+		return errors.New("no signing secret configured")
+	}
+
+	ts := h.Get("X-Slack-Request-Timestamp")
+	sent, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("invalid request timestamp")
+	}
+
+	age := now.Sub(time.Unix(sent, 0))
+	if age > maxRequestAge || age < -maxRequestAge {
+		return errors.New("request timestamp too old")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, b)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(h.Get("X-Slack-Signature"))) {
+		return errors.New("invalid request signature")
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package slack
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Example request taken from https://api.slack.com/authentication/verifying-requests-from-slack
+var signedBody = []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c")
+
+const signedSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+func signedHeader(ts string, sig string) http.Header {
+	h := http.Header{}
+	h.Set("X-Slack-Request-Timestamp", ts)
+	h.Set("X-Slack-Signature", sig)
+	return h
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1531420618, 0)
+	valid := "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503"
+
+	tests := map[string]struct {
+		secret   string
+		header   http.Header
+		now      time.Time
+		expected string
+	}{
+		"valid":         {secret: signedSecret, header: signedHeader("1531420618", valid), now: now, expected: ""},
+		"bad signature": {secret: signedSecret, header: signedHeader("1531420618", "v0=nope"), now: now, expected: "invalid request signature"},
+		"replayed":      {secret: signedSecret, header: signedHeader("1531420618", valid), now: now.Add(time.Hour), expected: "request timestamp too old"},
+		"no timestamp":  {secret: signedSecret, header: signedHeader("", valid), now: now, expected: "invalid request timestamp"},
+		"no secret":     {secret: "", header: signedHeader("1531420618", valid), now: now, expected: "no signing secret configured"},
+	}
+
+	for _, tc := range tests {
+		err := verify(tc.secret, tc.header, signedBody, tc.now)
+		if tc.expected == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tc.expected)
+		}
+	}
+}
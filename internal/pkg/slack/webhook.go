@@ -0,0 +1,118 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+type state struct {
+	emoji string
+	label string
+}
+
+// lifecycle defines the events that move an item between states. A bot
+// updates the original message for these, but an incoming webhook can't
+var lifecycle = map[arrhook.EventKind]state{
+	arrhook.EventMovieAdded: {emoji: ":large_green_circle:", label: "Added"},
+	arrhook.EventSeriesAdd:  {emoji: ":large_green_circle:", label: "Added"},
+	arrhook.EventGrab:       {emoji: ":large_orange_circle:", label: "Grabbed"},
+	arrhook.EventDownload:   {emoji: ":large_green_circle:", label: "Downloaded"},
+}
+
+// postWebhook posts a webhook to a route's incoming webhook. The first
+// event for an item gets a full message and later lifecycle events get
+// a compact state change, as incoming webhooks can't edit messages
+func (sc *Client) postWebhook(r config.Route, e *arr.Event) error {
+	_, tracked := lifecycle[e.Kind]
+
+	b := message(r, e, "")
+
+	var rec *record
+	if tracked {
+		rec = sc.load(r, e)
+		if last := rec.last(); last != nil && last.Type != arrhook.EventDownload {
+			b = onStateChange(r.Channel, e, last.Type)
+		}
+		rec = rec.advance(e, time.Now())
+	}
+
+	response, err := sc.send(r, "chat.postMessage", b)
+	if err != nil {
+		return err
+	}
+
+	if !response.OK {
+		slog.Error(response.Error)
+		return nil
+	}
+
+	if e.Kind == arrhook.EventMovieDelete || e.Kind == arrhook.EventSeriesDelete {
+		sc.forget(r, e)
+	} else if tracked {
+		sc.save(r, e, rec)
+	}
+
+	return nil
+}
+
+// send posts a message to a route, through its incoming webhook if it has one
+func (sc *Client) send(r config.Route, m string, b body) (*response, error) {
+	if r.Webhook == "" {
+		return sc.call(m, b)
+	}
+
+	// The channel is fixed by the webhook, and it can't thread or update
+	b.Channel, b.TS, b.ThreadTS = "", "", ""
+
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, r.Webhook, bytes.NewBuffer(jb))
+	req.Header.Add("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := sc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			slog.With("package", "slack").Error("Failed to close body")
+		}
+	}()
+
+	// Incoming webhooks reply with plain text rather than JSON
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return &response{OK: false, Error: string(body)}, nil
+	}
+
+	return &response{OK: true}, nil
+}
+
+func onStateChange(c string, e *arr.Event, previous arrhook.EventKind) body {
+	from, to := lifecycle[previous], lifecycle[e.Kind]
+	return body{
+		Channel: c,
+		Text:    fmt.Sprintf("%s: %s → %s", e.Title, from.label, to.label),
+		Blocks: []block{
+			{
+				Type: "context",
+				Elements: []text{
+					{Type: "mrkdwn", Text: fmt.Sprintf("%s <%s|%s>: %s → *%s*", to.emoji, e.URL, e.Title, from.label, to.label)},
+				},
+			},
+		},
+	}
+}
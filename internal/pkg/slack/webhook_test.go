@@ -0,0 +1,58 @@
+package slack
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/config"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
+)
+
+func TestOnStateChange(t *testing.T) {
+	expected := body{
+		Channel: "c123",
+		Text:    "Film (1970): Grabbed → Downloaded",
+		Blocks: []block{
+			{
+				Type: "context",
+				Elements: []text{
+					{Type: "mrkdwn", Text: ":large_green_circle: <http://localhost/movie/55|Film (1970)>: Grabbed → *Downloaded*"},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, expected, onStateChange("c123", radarrOnDownload.Event(), arrhook.EventGrab))
+}
+
+func TestSendWebhook(t *testing.T) {
+	var received body
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &received)
+		if received.Text == "fail" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid_payload"))
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	sc := Client{client: *server.Client()}
+	route := config.Route{Name: "hook", Webhook: server.URL}
+
+	resp, err := sc.send(route, "chat.update", body{Channel: "c123", TS: "1234", Text: "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, &response{OK: true}, resp)
+	assert.Equal(t, body{Text: "hello"}, received)
+
+	resp, err = sc.send(route, "chat.postMessage", body{Text: "fail"})
+	assert.NoError(t, err)
+	assert.Equal(t, &response{OK: false, Error: "invalid_payload"}, resp)
+}
@@ -0,0 +1,115 @@
+package sonarr
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Client defines a client for the parts of the Sonarr API gwarr uses
+type Client struct {
+	url    string
+	key    string
+	client http.Client
+}
+
+// APIEpisode defines an episode as returned by the Sonarr API
+type APIEpisode struct {
+	ID            int    `json:"id"`
+	SeriesID      int    `json:"seriesId"`
+	SeasonNumber  int    `json:"seasonNumber"`
+	EpisodeNumber int    `json:"episodeNumber"`
+	Title         string `json:"title"`
+	AirDateUTC    string `json:"airDateUtc,omitempty"`
+	HasFile       bool   `json:"hasFile"`
+	Monitored     bool   `json:"monitored"`
+}
+
+// timeout is how long a request to Sonarr may take, so a slow instance
+// can't hold up messages
+const timeout = 10 * time.Second
+
+// NewClient creates a client for the Sonarr instance at the given URL
+func NewClient(baseURL string, key string) *Client {
+	return &Client{url: baseURL, key: key, client: http.Client{Timeout: timeout}}
+}
+
+// Episodes returns every episode of a season of a series
+func (c *Client) Episodes(seriesID int, season int) ([]APIEpisode, error) {
+	q := url.Values{}
+	q.Set("seriesId", fmt.Sprint(seriesID))
+	q.Set("seasonNumber", fmt.Sprint(season))
+
+	episodes := []APIEpisode{}
+	err := c.get("/api/v3/episode?"+q.Encode(), &episodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return episodes, nil
+}
+
+// NextAiring returns when the next episode of a series airs, or the zero
+// time if Sonarr doesn't know of one
+func (c *Client) NextAiring(seriesID int) (time.Time, error) {
+	series := struct {
+		NextAiring time.Time `json:"nextAiring"`
+	}{}
+	err := c.get(fmt.Sprintf("/api/v3/series/%d", seriesID), &series)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return series.NextAiring, nil
+}
+
+// get decodes the response to a request for a path of the API into v
+func (c *Client) get(path string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("X-Api-Key", c.key)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			slog.With("package", "sonarr").Error("Failed to close body")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sonarr returned %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// SeasonProgress returns how many of a season's monitored episodes have
+// been imported, out of how many are monitored
+func (c *Client) SeasonProgress(seriesID int, season int) (int, int, error) {
+	episodes, err := c.Episodes(seriesID, season)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	have, total := 0, 0
+	for _, ep := range episodes {
+		if !ep.Monitored {
+			continue
+		}
+		total++
+		if ep.HasFile {
+			have++
+		}
+	}
+
+	return have, total, nil
+}
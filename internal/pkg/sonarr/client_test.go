@@ -0,0 +1,65 @@
+package sonarr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeasonProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		assert.Equal(t, "/api/v3/episode", r.URL.Path)
+		assert.Equal(t, "12", r.URL.Query().Get("seriesId"))
+		assert.Equal(t, "3", r.URL.Query().Get("seasonNumber"))
+
+		_ = json.NewEncoder(w).Encode([]APIEpisode{
+			{ID: 1, SeriesID: 12, SeasonNumber: 3, EpisodeNumber: 1, HasFile: true, Monitored: true},
+			{ID: 2, SeriesID: 12, SeasonNumber: 3, EpisodeNumber: 2, HasFile: true, Monitored: true},
+			{ID: 3, SeriesID: 12, SeasonNumber: 3, EpisodeNumber: 3, HasFile: false, Monitored: true},
+			{ID: 4, SeriesID: 12, SeasonNumber: 3, EpisodeNumber: 4, HasFile: false, Monitored: false},
+		})
+	}))
+	defer server.Close()
+
+	have, total, err := NewClient(server.URL, "secret").SeasonProgress(12, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, have)
+	assert.Equal(t, 3, total)
+
+	_, _, err = NewClient(server.URL, "wrong").SeasonProgress(12, 3)
+	assert.EqualError(t, err, "sonarr returned 401 Unauthorized")
+}
+
+func TestNextAiring(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/series/12":
+			_, _ = w.Write([]byte(`{"id": 12, "title": "Show", "nextAiring": "2026-10-20T01:00:00Z"}`))
+		case "/api/v3/series/13":
+			_, _ = w.Write([]byte(`{"id": 13, "title": "Ended Show"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	next, err := NewClient(server.URL, "secret").NextAiring(12)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 20, 1, 0, 0, 0, time.UTC), next)
+
+	next, err = NewClient(server.URL, "secret").NextAiring(13)
+	assert.NoError(t, err)
+	assert.True(t, next.IsZero())
+
+	_, err = NewClient(server.URL, "secret").NextAiring(14)
+	assert.EqualError(t, err, "sonarr returned 404 Not Found")
+}
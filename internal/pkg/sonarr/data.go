@@ -1,146 +1,439 @@
 package sonarr
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
 )
 
-// ParseError defines a custom error type for failing to turn
-// a webhook into a sonarr.Data struct
-type ParseError struct{}
-
-func (pe *ParseError) Error() string {
-	return "Unable to parse webhook"
-}
-
-type Data struct {
-	ApplicationURL     string       `json:"applicationUrl,omitempty"`
-	DownloadClient     string       `json:"downloadClient,omitempty"`
-	DownloadClientType string       `json:"downloadClientType,omitempty"`
-	DownloadID         string       `json:"downloadId,omitempty"`
-	EpisodeFile        *EpisodeFile `json:"episodeFile,omitempty"`
-	Episodes           []Episode    `json:"episodes,omitempty"`
-	EventType          string       `json:"eventType,omitempty"`
-	Release            Release      `json:"release,omitempty"`
-	Series             Series       `json:"series,omitempty"`
-}
-
-type Series struct {
-	ID       int    `json:"id,omitempty"`
-	Title    string `json:"title,omitempty"`
-	Path     string `json:"path,omitempty"`
-	TVDBID   int    `json:"tvdbId,omitempty"`
-	TVMazeID int    `json:"tvMazeId,omitempty"`
-	IMDBID   string `json:"imdbId,omitempty"`
-	Type     string `json:"type,omitempty"`
-}
-
-type Episode struct {
-	ID            int    `json:"id,omitempty"`
-	EpisodeNumber int    `json:"episodeNumber,omitempty"`
-	SeasonNumber  int    `json:"seasonNumber,omitempty"`
-	Title         string `json:"title,omitempty"`
-	AirDate       string `json:"airDate,omitempty"`
-	AirDateUTC    string `json:"airDateUtc,omitempty"`
-}
-
-// EpisodeFile defines metadata about a local movie file
-type EpisodeFile struct {
-	ID             int    `json:"id,omitempty"`
-	RelativePath   string `json:"relativePath,omitempty"`
-	Path           string `json:"path,omitempty"`
-	Quality        string `json:"quality,omitempty"`
-	QualityVersion int    `json:"qualityVersion,omitempty"`
-	ReleaseGroup   string `json:"releaseGroup,omitempty"`
-	SceneName      string `json:"sceneName,omitempty"`
-	IndexerFlags   string `json:"indexerFlags,omitempty"`
-	Size           int    `json:"size,omitempty"`
-}
+// Data defines the structure of a Sonarr webhook
+type Data arrhook.Sonarr
+
+// Series defines a series
+type Series = arr.Series
+
+// Season defines a season of a series
+type Season = arr.Season
+
+// Episode defines an episode of a series
+type Episode = arr.Episode
 
 // Release defines metadata about an episode release
-type Release struct {
-	Quality        string `json:"quality,omitempty"`
-	QualityVersion int    `json:"qualityVersion,omitempty"`
-	ReleaseGroup   string `json:"releaseGroup,omitempty"`
-	ReleaseTitle   string `json:"releaseTitle,omitempty"`
-	Indexer        string `json:"indexer,omitempty"`
-	Size           int    `json:"size,omitempty"`
-}
+type Release = arr.Release
+
+// Language defines a language of a release or file. Sonarr v3 doesn't
+// send languages
+type Language = arr.Language
+
+// EpisodeFile defines metadata about a local episode file
+type EpisodeFile = arr.File
+
+// DeletedFiles is sent as a bool on SeriesDelete, and as the replaced
+// files on an upgrade Download
+type DeletedFiles = arr.DeletedFiles
+
+// RenamedEpisodeFiles defines metadata about an episode file rename
+type RenamedEpisodeFiles = arr.RenamedFile
+
+// CustomFormatInfo defines the custom formats a release matched
+type CustomFormatInfo = arr.CustomFormatInfo
+
+// CustomFormat defines a custom format set up in Sonarr
+type CustomFormat = arr.CustomFormat
+
+// DownloadInfo defines the download client's view of a release
+type DownloadInfo = arr.DownloadInfo
+
+// StatusMessage defines why a download can't be imported
+type StatusMessage = arr.StatusMessage
 
 // ParseWebhook takes a webhook and turns it into a struct
 func ParseWebhook(body []byte) (*Data, error) {
-	d := Data{}
-
-	err := json.Unmarshal(body, &d)
+	s, err := arrhook.ParseSonarr(body)
 	if err != nil {
-		fmt.Println(err)
-		slog.With("package", "sonarr").Error("Invalid JSON")
-		return nil, &ParseError{}
+		slog.With("package", "sonarr").Error("Bad Webhook")
+		return nil, err
 	}
 
-	if d.Series.ID == 0 {
-		fmt.Println(err)
-		slog.With("package", "sonarr").Error("Bad Webhook")
-		return nil, &ParseError{}
+	return (*Data)(s), nil
+}
+
+// series returns the series the webhook is about, which is empty for
+// events that aren't about one
+func (d *Data) series() Series {
+	if d.Series == nil {
+		return Series{}
+	}
+	return *d.Series
+}
+
+// release returns the release the webhook is about, which is empty for
+// events without one
+func (d *Data) release() Release {
+	if d.Release == nil {
+		return Release{}
 	}
+	return *d.Release
+}
 
-	slog.Debug(fmt.Sprintf("%#v", d))
+var (
+	slugInvalid   = regexp.MustCompile(`[^a-z0-9\s_-]+`)
+	slugSeparator = regexp.MustCompile(`[\s-]+`)
+)
 
-	return &d, nil
+// accented lists the lowercase Latin letters that decompose into an ASCII
+// letter and combining marks, by that letter
+var accented = map[string]string{
+	"a": "àáâãäåāăą",
+	"c": "çćĉċč",
+	"d": "ď",
+	"e": "èéêëēĕėęě",
+	"g": "ĝğġģ",
+	"h": "ĥ",
+	"i": "ìíîïĩīĭį",
+	"j": "ĵ",
+	"k": "ķ",
+	"l": "ĺļľ",
+	"n": "ñńņň",
+	"o": "òóôõöōŏő",
+	"r": "ŕŗř",
+	"s": "śŝşš",
+	"t": "ţť",
+	"u": "ùúûüũūŭůűų",
+	"w": "ŵ",
+	"y": "ýÿŷ",
+	"z": "źżž",
 }
 
+// unaccent transliterates accented letters to ASCII the way Sonarr does,
+// by decomposing them and dropping the combining marks
+var unaccent = func() *strings.Replacer {
+	pairs := []string{}
+	for base, letters := range accented {
+		for _, l := range letters {
+			pairs = append(pairs, string(l), base)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// urlID returns the slug Sonarr uses in series links. Older versions of
+// Sonarr don't send it, so it is worked out from the title the same way
+// Sonarr does, though that can't account for Sonarr disambiguating
+// series with the same name
 func (d *Data) urlID() string {
-	lowered := strings.ToLower(d.Series.Title)
-	lowered = regexp.MustCompile(`[^a-zA-Z0-9 ]+`).ReplaceAllString(lowered, "")
-	split := strings.Split(lowered, " ")
-	joined := strings.Join(split, "-")
-	return joined
+	series := d.series()
+	if series.TitleSlug != "" {
+		return series.TitleSlug
+	}
+
+	slug := slugInvalid.ReplaceAllString(unaccent.Replace(strings.ToLower(series.Title)), "")
+	slug = slugSeparator.ReplaceAllString(slug, "-")
+	return strings.Trim(slug, "-_")
+}
+
+// DeepURL links to the season of the episodes a webhook is about, or the
+// series when it isn't about any
+func (d *Data) DeepURL() string {
+	if len(d.Episodes) == 0 {
+		return d.URL()
+	}
+	return fmt.Sprintf("%s#season-%d", d.URL(), d.Episodes[0].SeasonNumber)
+}
+
+func (d *Data) Kind() arrhook.EventKind { return d.EventType }
+func (d *Data) Instance() string        { return d.InstanceName }
+func (d *Data) URL() string             { return fmt.Sprintf("%s/series/%s", d.ApplicationURL, d.urlID()) }
+func (d *Data) Service() string         { return "sonarr" }
+
+// eventKind returns the type of event. Sonarr v4 can send one ImportComplete
+// for a release rather than a Download for each file, which is handled as a
+// Download of the whole release. With both turned on the release is sent
+// twice, so the notifier drops an ImportComplete whose files were already
+// downloaded
+func (d *Data) eventKind() arrhook.EventKind {
+	if d.EventType == arrhook.EventImportComplete {
+		return arrhook.EventDownload
+	}
+	return d.EventType
 }
 
-func (d *Data) IMDBID() string  { return d.Series.IMDBID }
-func (d *Data) Type() string    { return d.EventType }
-func (d *Data) URL() string     { return fmt.Sprintf("%s/series/%s", d.ApplicationURL, d.urlID()) }
-func (d *Data) Service() string { return "sonarr" }
+// wholeSeries returns true if the webhook is about a series as a whole,
+// or about no series at all, rather than some of its episodes
+func (d *Data) wholeSeries() bool {
+	return d.EventType == arrhook.EventSeriesDelete || d.EventType == arrhook.EventSeriesAdd || len(d.Episodes) == 0
+}
 
 func (d *Data) ID() int {
-	if d.EventType == "SeriesDelete" || d.EventType == "SeriesAdd" {
-		return d.Series.ID
+	if d.wholeSeries() {
+		return d.series().ID
 	}
 	return d.Episodes[0].ID
 }
 
 func (d *Data) ReleaseDate() string {
-	if d.EventType == "SeriesDelete" || d.EventType == "SeriesAdd" {
+	if d.wholeSeries() {
 		return "N/A"
 	}
 	return d.Episodes[0].AirDate
 }
 
 func (d *Data) Year() string {
-	if d.EventType == "SeriesDelete" || d.EventType == "SeriesAdd" {
+	if year := d.series().Year; year != 0 {
+		return fmt.Sprint(year)
+	}
+	if d.wholeSeries() {
 		return "N/A"
 	}
 	return d.Episodes[0].AirDate
 }
 
 func (d *Data) Title() string {
-	ep := d.Episodes[0]
-	return fmt.Sprintf("%s - %dx%02d - %s", d.Series.Title, ep.SeasonNumber, ep.EpisodeNumber, ep.Title)
+	switch d.EventType {
+	case arrhook.EventHealth, arrhook.EventHealthRestored:
+		return d.Message
+	case arrhook.EventApplicationUpdate:
+		return fmt.Sprintf("%s → %s", d.PreviousVersion, d.NewVersion)
+	}
+	title := d.series().Title
+	if len(d.Episodes) == 0 {
+		if title == "" && d.DownloadInfo != nil {
+			return d.DownloadInfo.Title
+		}
+		return title
+	}
+	if len(d.Episodes) > 1 {
+		return fmt.Sprintf("%s - %s", title, d.episodeRange())
+	}
+	return d.format(d.Episodes[0])
+}
+
+// formats are the title formats for an episode of each type of series
+var formats = map[string]string{
+	"standard": "{series} - {season}x{episode} - {title}",
+	"daily":    "{series} - {airdate} - {title}",
+	"anime":    "{series} - #{absolute} - {title}",
 }
 
-func (d *Data) Quality() string {
-	if d.EventType == "Grab" {
-		return d.Release.Quality
+// SetFormats replaces the title formats for the given series types.
+// Formats can use {series}, {season}, {episode}, {absolute}, {airdate}
+// and {title}
+func SetFormats(f map[string]string) {
+	for seriesType, format := range f {
+		formats[seriesType] = format
 	}
-	return d.EpisodeFile.Quality
 }
 
-func (d *Data) ReleaseGroup() string {
-	if d.EventType == "Grab" {
-		return d.Release.ReleaseGroup
+// format titles an episode by the type of its series. Episodes without
+// the absolute number or air date their type needs fall back to the
+// standard format
+func (d *Data) format(ep Episode) string {
+	series := d.series()
+	format, ok := formats[series.Type]
+	if !ok || (series.Type == "anime" && ep.AbsoluteEpisodeNumber == 0) || (series.Type == "daily" && ep.AirDate == "") {
+		format = formats["standard"]
+	}
+
+	return strings.NewReplacer(
+		"{series}", series.Title,
+		"{season}", fmt.Sprint(ep.SeasonNumber),
+		"{episode}", fmt.Sprintf("%02d", ep.EpisodeNumber),
+		"{absolute}", fmt.Sprint(ep.AbsoluteEpisodeNumber),
+		"{airdate}", ep.AirDate,
+		"{title}", ep.Title,
+	).Replace(format)
+}
+
+// episodeMarker matches the episode number in a release title, which a
+// season pack doesn't have
+var episodeMarker = regexp.MustCompile(`(?i)S\d+E\d+`)
+
+// episodeRange describes the episodes of a multi-episode webhook, like
+// "S03E01–E10" or "Season 3 (10 episodes)"
+func (d *Data) episodeRange() string {
+	eps := slices.Clone(d.Episodes)
+	slices.SortFunc(eps, func(a, b Episode) int {
+		if a.SeasonNumber != b.SeasonNumber {
+			return a.SeasonNumber - b.SeasonNumber
+		}
+		return a.EpisodeNumber - b.EpisodeNumber
+	})
+	first, last := eps[0], eps[len(eps)-1]
+
+	if first.SeasonNumber != last.SeasonNumber {
+		return fmt.Sprintf("Seasons %d–%d (%d episodes)", first.SeasonNumber, last.SeasonNumber, len(eps))
 	}
-	return d.EpisodeFile.ReleaseGroup
+
+	if d.series().Type == "anime" && first.AbsoluteEpisodeNumber != 0 && last.AbsoluteEpisodeNumber != 0 {
+		return fmt.Sprintf("#%d–%d", first.AbsoluteEpisodeNumber, last.AbsoluteEpisodeNumber)
+	}
+
+	if d.seasonRelease() {
+		return fmt.Sprintf("Season %d (%d episodes)", first.SeasonNumber, len(eps))
+	}
+
+	if last.EpisodeNumber-first.EpisodeNumber == len(eps)-1 {
+		return fmt.Sprintf("S%02dE%02d–E%02d", first.SeasonNumber, first.EpisodeNumber, last.EpisodeNumber)
+	}
+
+	numbers := []string{fmt.Sprintf("S%02dE%02d", first.SeasonNumber, first.EpisodeNumber)}
+	for _, ep := range eps[1:] {
+		numbers = append(numbers, fmt.Sprintf("E%02d", ep.EpisodeNumber))
+	}
+	return strings.Join(numbers, ", ")
+}
+
+// SeasonPack returns true if a grab is for a whole season
+func (d *Data) SeasonPack() bool {
+	if d.EventType != arrhook.EventGrab || len(d.Episodes) < 2 {
+		return false
+	}
+
+	for _, ep := range d.Episodes {
+		if ep.SeasonNumber != d.Episodes[0].SeasonNumber {
+			return false
+		}
+	}
+
+	return d.seasonRelease()
+}
+
+// seasonRelease returns true if the release is a season pack. Sonarr v4
+// says what type of release it is, and v3 releases are guessed from
+// whether their title has an episode number
+func (d *Data) seasonRelease() bool {
+	r := d.release()
+	switch r.ReleaseType {
+	case "seasonPack":
+		return true
+	case "singleEpisode", "multiEpisode":
+		return false
+	}
+
+	return r.ReleaseTitle != "" && !episodeMarker.MatchString(r.ReleaseTitle)
+}
+
+// files returns the episode files a webhook is about. ImportComplete
+// sends every file of the release, and other events send one
+func (d *Data) files() []EpisodeFile {
+	if len(d.EpisodeFiles) > 0 {
+		return d.EpisodeFiles
+	}
+	if d.EpisodeFile != nil {
+		return []EpisodeFile{*d.EpisodeFile}
+	}
+	return nil
+}
+
+// Languages returns the languages of a grabbed release or imported files
+func (d *Data) Languages() []string {
+	languages := []Language{}
+	if d.EventType == arrhook.EventGrab {
+		languages = d.release().Languages
+	}
+	for _, f := range d.files() {
+		languages = append(languages, f.Languages...)
+	}
+
+	names := []string{}
+	for _, l := range languages {
+		if !slices.Contains(names, l.Name) {
+			names = append(names, l.Name)
+		}
+	}
+	return names
+}
+
+// ImportPaths returns where imported files were moved from and to.
+// Sonarr v4 sends them for the whole release on ImportComplete, and for
+// the file on Download
+func (d *Data) ImportPaths() *arr.ImportPaths {
+	if d.SourcePath != "" {
+		return &arr.ImportPaths{Source: d.SourcePath, Destination: d.DestinationPath}
+	}
+	if d.EventType == arrhook.EventDownload && d.EpisodeFile != nil && d.EpisodeFile.SourcePath != "" {
+		return &arr.ImportPaths{Source: d.EpisodeFile.SourcePath, Destination: d.EpisodeFile.Path}
+	}
+	return nil
+}
+
+// SeriesInfo returns the series metadata for SeriesAdd and SeriesDelete events
+func (d *Data) SeriesInfo() *arr.SeriesInfo {
+	if d.EventType != arrhook.EventSeriesAdd && d.EventType != arrhook.EventSeriesDelete {
+		return nil
+	}
+
+	series := d.series()
+	info := arr.SeriesInfo{
+		Year:         series.Year,
+		Network:      series.Network,
+		Monitored:    series.Monitored,
+		FilesDeleted: d.DeletedFiles.Deleted,
+	}
+	for _, season := range series.Seasons {
+		// Specials are season 0, and aren't counted as a season
+		if season.SeasonNumber > 0 {
+			info.Seasons++
+		}
+	}
+
+	return &info
+}
+
+// Event normalizes the webhook into an arr.Event. The subject is the
+// series, even when the event is about some of its episodes
+func (d *Data) Event() *arr.Event {
+	series := d.series()
+	e := arr.Event{
+		Source:      d.Service(),
+		Instance:    d.InstanceName,
+		Kind:        d.eventKind(),
+		Name:        d.EventName,
+		ID:          d.ID(),
+		Title:       d.Title(),
+		URL:         d.URL(),
+		DeepURL:     d.DeepURL(),
+		ReleaseDate: d.ReleaseDate(),
+		Upgrade:     d.IsUpgrade,
+		Subject: arr.Subject{
+			ID:       series.ID,
+			Title:    series.Title,
+			Year:     series.Year,
+			IMDBID:   series.IMDBID,
+			TVDBID:   series.TVDBID,
+			URL:      d.URL(),
+			Path:     series.Path,
+			Tags:     series.Tags,
+			Episodes: d.Episodes,
+		},
+		DownloadID:         d.DownloadID,
+		DownloadClient:     d.DownloadClient,
+		DownloadClientType: d.DownloadClientType,
+		CustomFormats:      d.CustomFormatInfo,
+		Languages:          d.Languages(),
+		ImportPaths:        d.ImportPaths(),
+		Replaced:           d.DeletedFiles.Files,
+		DeleteReason:       d.DeleteReason,
+		Renames:            d.RenamedEpisodeFiles,
+		Health:             (*arrhook.Sonarr)(d).Health(),
+		Update:             (*arrhook.Sonarr)(d).Update(),
+		Series:             d.SeriesInfo(),
+		SeasonPack:         d.SeasonPack(),
+		Files:              d.files(),
+	}
+
+	if d.EventType == arrhook.EventManualInteractionRequired {
+		e.Interaction = arr.NewInteraction(d.ApplicationURL, d.DownloadInfo, d.DownloadStatus, d.StatusMessages)
+	}
+
+	if r := d.release(); r.ReleaseTitle != "" || r.Quality != "" {
+		e.Release = d.Release
+	}
+
+	return &e
 }
@@ -1,9 +1,15 @@
 package sonarr
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/mbarrin/gwarr/internal/pkg/arr"
+	"github.com/mbarrin/gwarr/pkg/arrhook"
 )
 
 var testJSON = []byte(`{
@@ -21,7 +27,7 @@ var testJSON = []byte(`{
 }`)
 
 var testSonarr = &Data{
-	Series: Series{
+	Series: &Series{
 		ID:       1,
 		Title:    "Test Title",
 		Path:     "/path/to/file",
@@ -37,7 +43,8 @@ var testSonarr = &Data{
 			Title:         "Test title",
 		},
 	},
-	EventType:      "Test",
+	EventType:      arrhook.EventTest,
+	EventName:      "Test",
 	ApplicationURL: "http://localhost",
 }
 
@@ -75,7 +82,7 @@ var grabJSON = []byte(`{
 }`)
 
 var grabSonarr = &Data{
-	Series: Series{
+	Series: &Series{
 		ID:       1,
 		Title:    "Test Title",
 		Path:     "/path/to/show",
@@ -94,7 +101,7 @@ var grabSonarr = &Data{
 			AirDateUTC:    "1970-01-01T00:00:00Z",
 		},
 	},
-	Release: Release{
+	Release: &Release{
 		Quality:        "WEBDL-1080p",
 		QualityVersion: 1,
 		ReleaseGroup:   "legit",
@@ -105,7 +112,8 @@ var grabSonarr = &Data{
 	DownloadClient:     "sab",
 	DownloadClientType: "usenet",
 	DownloadID:         "SABnzbd_nzo_dsionq_f",
-	EventType:          "Grab",
+	EventType:          arrhook.EventGrab,
+	EventName:          "Grab",
 	ApplicationURL:     "http://localhost",
 }
 
@@ -146,7 +154,7 @@ var downloadJSON = []byte(`{
 }`)
 
 var downloadSonarr = &Data{
-	Series: Series{
+	Series: &Series{
 		ID:       1,
 		Title:    "Test Title",
 		Path:     "/path/to/show",
@@ -178,10 +186,30 @@ var downloadSonarr = &Data{
 	DownloadClient:     "sab",
 	DownloadClientType: "usenet",
 	DownloadID:         "SABnzbd_nzo_dsionq_f",
-	EventType:          "Download",
+	EventType:          arrhook.EventDownload,
+	EventName:          "Download",
 	ApplicationURL:     "http://localhost",
 }
 
+var healthRestoredJSON = []byte(`{
+	"level": "error",
+	"message": "Disk space is low",
+	"type": "DiskSpaceCheck",
+	"wikiUrl": "https://wiki.servarr.com/sonarr/system#disk-space",
+	"eventType": "HealthRestored",
+	"applicationUrl": "http://localhost"
+}`)
+
+var healthRestoredSonarr = &Data{
+	EventType:      arrhook.EventHealthRestored,
+	EventName:      "HealthRestored",
+	ApplicationURL: "http://localhost",
+	Level:          "error",
+	Message:        "Disk space is low",
+	HealthType:     "DiskSpaceCheck",
+	WikiURL:        "https://wiki.servarr.com/sonarr/system#disk-space",
+}
+
 func TestParseWebhook(t *testing.T) {
 	tests := map[string]struct {
 		input        []byte
@@ -191,8 +219,18 @@ func TestParseWebhook(t *testing.T) {
 		"test":       {input: testJSON, expectedData: testSonarr, expectedErr: nil},
 		"grabbed":    {input: grabJSON, expectedData: grabSonarr, expectedErr: nil},
 		"downloaded": {input: downloadJSON, expectedData: downloadSonarr, expectedErr: nil},
-		"malformed":  {input: []byte("}"), expectedData: nil, expectedErr: &ParseError{}},
-		"invalid":    {input: []byte("{}"), expectedData: nil, expectedErr: &ParseError{}},
+		"health":     {input: healthRestoredJSON, expectedData: healthRestoredSonarr, expectedErr: nil},
+		"connection test": {
+			input:        []byte(`{"eventType": "Test", "instanceName": "Sonarr"}`),
+			expectedData: &Data{EventType: arrhook.EventTest, EventName: "Test", InstanceName: "Sonarr"},
+			expectedErr:  nil,
+		},
+		"malformed": {input: []byte("}"), expectedData: nil, expectedErr: &arr.ParseError{}},
+		"invalid":   {input: []byte(`{"eventType": "Grab"}`), expectedData: nil, expectedErr: &arr.ParseError{}},
+		"empty":     {input: []byte(`{}`), expectedData: nil, expectedErr: &arr.ParseError{}},
+		"null":      {input: []byte(`null`), expectedData: nil, expectedErr: &arr.ParseError{}},
+		"no type":   {input: []byte(`{"series": {"id": 1}}`), expectedData: nil, expectedErr: &arr.ParseError{}},
+		"unknown":   {input: []byte(`{"eventType": "SeriesRetitled"}`), expectedData: &Data{EventType: arrhook.EventUnknown, EventName: "SeriesRetitled"}, expectedErr: nil},
 	}
 
 	for _, tc := range tests {
@@ -212,13 +250,18 @@ func TestURLID(t *testing.T) {
 		data     Data
 		expected string
 	}{
-		"single word":           {data: Data{ApplicationURL: "http://localhost", Series: Series{Title: "Show"}}, expected: "http://localhost/series/show"},
-		"single word with year": {data: Data{ApplicationURL: "http://localhost", Series: Series{Title: "Show 2016"}}, expected: "http://localhost/series/show-2016"},
-		"multi word":            {data: Data{ApplicationURL: "http://localhost", Series: Series{Title: "Show Name Here"}}, expected: "http://localhost/series/show-name-here"},
-		"multi word with year":  {data: Data{ApplicationURL: "http://localhost", Series: Series{Title: "Show Name Here 2020"}}, expected: "http://localhost/series/show-name-here-2020"},
-		"! symbol":              {data: Data{ApplicationURL: "http://localhost", Series: Series{Title: "Bang!"}}, expected: "http://localhost/series/bang"},
-		": symbol":              {data: Data{ApplicationURL: "http://localhost", Series: Series{Title: "Show: Title"}}, expected: "http://localhost/series/show-title"},
-		"multi symbol":          {data: Data{ApplicationURL: "http://localhost", Series: Series{Title: "lol` wow`> 1970"}}, expected: "http://localhost/series/lol-wow-1970"},
+		"single word":           {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "Show"}}, expected: "http://localhost/series/show"},
+		"single word with year": {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "Show 2016"}}, expected: "http://localhost/series/show-2016"},
+		"multi word":            {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "Show Name Here"}}, expected: "http://localhost/series/show-name-here"},
+		"multi word with year":  {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "Show Name Here 2020"}}, expected: "http://localhost/series/show-name-here-2020"},
+		"! symbol":              {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "Bang!"}}, expected: "http://localhost/series/bang"},
+		": symbol":              {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "Show: Title"}}, expected: "http://localhost/series/show-title"},
+		"title slug":            {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "The Office (US)", TitleSlug: "the-office-us"}}, expected: "http://localhost/series/the-office-us"},
+		"duplicate name slug":   {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "Show", TitleSlug: "show-2016"}}, expected: "http://localhost/series/show-2016"},
+		"dash":                  {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "Show - The Movie"}}, expected: "http://localhost/series/show-the-movie"},
+		"multi symbol":          {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "lol` wow`> 1970"}}, expected: "http://localhost/series/lol-wow-1970"},
+		"accented":              {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "Pokémon Horizons"}}, expected: "http://localhost/series/pokemon-horizons"},
+		"accented capitals":     {data: Data{ApplicationURL: "http://localhost", Series: &Series{Title: "ÉLITE"}}, expected: "http://localhost/series/elite"},
 	}
 
 	for _, tc := range tests {
@@ -226,3 +269,314 @@ func TestURLID(t *testing.T) {
 		assert.Equal(t, actual, tc.expected)
 	}
 }
+
+func TestHealthEvents(t *testing.T) {
+	assert.Equal(t, 0, healthRestoredSonarr.ID())
+	assert.Equal(t, "Disk space is low", healthRestoredSonarr.Title())
+	assert.Equal(t, "N/A", healthRestoredSonarr.ReleaseDate())
+	assert.Equal(t, "DiskSpaceCheck", healthRestoredSonarr.Event().Health.Type)
+}
+
+func TestInteraction(t *testing.T) {
+	input := []byte(`{
+	"series": {"id": 1, "title": "Show", "tvdbId": 123},
+	"episodes": [{"id": 5, "episodeNumber": 1, "seasonNumber": 1, "title": "Pilot"}],
+	"downloadInfo": {"quality": "WEBDL-1080p", "title": "Show.S01E01.1080p.WEB-GroupX", "size": 100},
+	"downloadClient": "SABnzbd",
+	"downloadId": "SABnzbd_nzo_1",
+	"downloadStatus": "Warning",
+	"downloadStatusMessages": [{"title": "Show.S01E01.1080p.WEB-GroupX", "messages": ["Episode was unexpected considering the folder name"]}],
+	"eventType": "ManualInteractionRequired",
+	"applicationUrl": "http://localhost"
+	}`)
+
+	d, err := ParseWebhook(input)
+	assert.NoError(t, err)
+	assert.Equal(t, "SABnzbd_nzo_1", d.DownloadID)
+	assert.Equal(t, "SABnzbd", d.Event().DownloadClient)
+	assert.Equal(t, &arr.Interaction{
+		ReleaseTitle: "Show.S01E01.1080p.WEB-GroupX",
+		Status:       "Warning",
+		Messages:     []string{"Episode was unexpected considering the folder name"},
+		QueueURL:     "http://localhost/activity/queue",
+	}, d.Event().Interaction)
+}
+
+func TestCustomFormats(t *testing.T) {
+	d, err := ParseWebhook([]byte(`{
+	"series": {"id": 1, "title": "Show"},
+	"episodes": [{"id": 5, "episodeNumber": 1, "seasonNumber": 1, "title": "Pilot"}],
+	"customFormatInfo": {"customFormats": [], "customFormatScore": -10},
+	"eventType": "Download"
+	}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &arr.CustomFormatInfo{CustomFormats: []arr.CustomFormat{}, CustomFormatScore: -10}, d.Event().CustomFormats)
+}
+
+func TestGrab(t *testing.T) {
+	e := grabSonarr.Event()
+	assert.Equal(t, "Test.Title.S01E01.Multi.1080p.WEB-DL.legit.mkv", e.Release.ReleaseTitle)
+	assert.Equal(t, "usenet", e.Release.Indexer)
+	assert.Equal(t, "sab", e.DownloadClient)
+	assert.Equal(t, "usenet", e.DownloadClientType)
+	assert.Nil(t, downloadSonarr.Event().Release)
+}
+
+func TestTags(t *testing.T) {
+	d, err := ParseWebhook([]byte(`{"series": {"id": 1, "title": "Show", "tags": ["requested-by-alice"]}, "eventType": "SeriesAdd"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"requested-by-alice"}, d.Event().Subject.Tags)
+}
+
+func TestMultiEpisodeTitle(t *testing.T) {
+	episodes := func(season int, numbers ...int) []Episode {
+		eps := []Episode{}
+		for _, n := range numbers {
+			eps = append(eps, Episode{ID: n, SeasonNumber: season, EpisodeNumber: n, Title: fmt.Sprintf("Part %d", n)})
+		}
+		return eps
+	}
+
+	tests := map[string]struct {
+		data     Data
+		expected string
+	}{
+		"single":      {data: Data{Series: &Series{Title: "Show"}, Episodes: episodes(3, 1)}, expected: "Show - 3x01 - Part 1"},
+		"double":      {data: Data{Series: &Series{Title: "Show"}, Episodes: episodes(3, 2, 1)}, expected: "Show - S03E01–E02"},
+		"range":       {data: Data{Series: &Series{Title: "Show"}, Episodes: episodes(3, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10), Release: &Release{ReleaseTitle: "Show.S03E01-E10.1080p"}}, expected: "Show - S03E01–E10"},
+		"season pack": {data: Data{Series: &Series{Title: "Show"}, Episodes: episodes(3, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10), Release: &Release{ReleaseTitle: "Show.S03.1080p.WEB-DL"}}, expected: "Show - Season 3 (10 episodes)"},
+		"gaps":        {data: Data{Series: &Series{Title: "Show"}, Episodes: episodes(3, 1, 3, 7)}, expected: "Show - S03E01, E03, E07"},
+		"seasons":     {data: Data{Series: &Series{Title: "Show"}, Episodes: append(episodes(1, 1, 2), episodes(2, 1)...)}, expected: "Show - Seasons 1–2 (3 episodes)"},
+	}
+
+	for name, tc := range tests {
+		assert.Equal(t, tc.expected, tc.data.Title(), name)
+	}
+}
+
+func TestDeepURL(t *testing.T) {
+	d := Data{ApplicationURL: "http://localhost", Series: &Series{TitleSlug: "show"}, Episodes: []Episode{{SeasonNumber: 3, EpisodeNumber: 1}}}
+	assert.Equal(t, "http://localhost/series/show#season-3", d.DeepURL())
+
+	d.Episodes = nil
+	assert.Equal(t, "http://localhost/series/show", d.DeepURL())
+}
+
+var seriesAddJSON = []byte(`{
+	"series": {
+		"id": 12,
+		"title": "Show",
+		"titleSlug": "show",
+		"tvdbId": 1234,
+		"imdbId": "tt0000012",
+		"type": "standard",
+		"year": 2016,
+		"network": "HBO",
+		"monitored": true,
+		"seasons": [{"seasonNumber": 0, "monitored": false}, {"seasonNumber": 1, "monitored": true}, {"seasonNumber": 2, "monitored": true}]
+	},
+	"eventType": "SeriesAdd",
+	"applicationUrl": "http://localhost"
+	}`)
+
+func TestSeriesEvents(t *testing.T) {
+	d, err := ParseWebhook(seriesAddJSON)
+	assert.NoError(t, err)
+
+	monitored := true
+	assert.Equal(t, &arr.SeriesInfo{Year: 2016, Network: "HBO", Seasons: 2, Monitored: &monitored}, d.SeriesInfo())
+	assert.Equal(t, 12, d.ID())
+	assert.Equal(t, "Show", d.Title())
+	assert.Equal(t, "N/A", d.ReleaseDate())
+	assert.Equal(t, "2016", d.Year())
+	assert.Equal(t, "", d.Event().Quality())
+	assert.Equal(t, "", d.Event().ReleaseGroup())
+	assert.Equal(t, 0, d.Event().Size())
+
+	d, err = ParseWebhook([]byte(`{"series": {"id": 12, "title": "Show"}, "deletedFiles": true, "eventType": "SeriesDelete"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &arr.SeriesInfo{FilesDeleted: true}, d.SeriesInfo())
+
+	assert.Nil(t, grabSonarr.SeriesInfo())
+}
+
+func TestRenames(t *testing.T) {
+	d, err := ParseWebhook([]byte(`{
+	"series": {"id": 12, "title": "Show"},
+	"renamedEpisodeFiles": [{"previousRelativePath": "Season 1/show.s01e01.mkv", "relativePath": "Season 1/Show - S01E01 - Pilot.mkv", "quality": "WEBDL-1080p"}],
+	"eventType": "Rename"
+	}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []arr.RenamedFile{{PreviousRelativePath: "Season 1/show.s01e01.mkv", RelativePath: "Season 1/Show - S01E01 - Pilot.mkv", Quality: "WEBDL-1080p"}}, d.Event().Renames)
+	assert.Equal(t, "Show", d.Title())
+}
+
+func TestEpisodeFileDelete(t *testing.T) {
+	d, err := ParseWebhook([]byte(`{
+	"series": {"id": 12, "title": "Show"},
+	"episodes": [{"id": 5, "episodeNumber": 1, "seasonNumber": 1, "title": "Pilot"}],
+	"episodeFile": {"id": 9, "relativePath": "Season 1/Show - S01E01 - Pilot.mkv", "quality": "WEBDL-720p", "size": 100},
+	"deleteReason": "missingFromDisk",
+	"eventType": "EpisodeFileDelete"
+	}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "missingFromDisk", d.Event().DeleteReason)
+	assert.Equal(t, "Show - 1x01 - Pilot", d.Title())
+	assert.Equal(t, "WEBDL-720p", d.Event().Quality())
+}
+
+func TestSeriesTypeTitle(t *testing.T) {
+	ep := Episode{SeasonNumber: 21, EpisodeNumber: 3, AbsoluteEpisodeNumber: 1071, Title: "The Return", AirDate: "2023-07-09"}
+
+	tests := map[string]struct {
+		data     Data
+		expected string
+	}{
+		"standard":           {data: Data{Series: &Series{Title: "Show", Type: "standard"}, Episodes: []Episode{ep}}, expected: "Show - 21x03 - The Return"},
+		"no type":            {data: Data{Series: &Series{Title: "Show"}, Episodes: []Episode{ep}}, expected: "Show - 21x03 - The Return"},
+		"daily":              {data: Data{Series: &Series{Title: "Show", Type: "daily"}, Episodes: []Episode{ep}}, expected: "Show - 2023-07-09 - The Return"},
+		"anime":              {data: Data{Series: &Series{Title: "Show", Type: "anime"}, Episodes: []Episode{ep}}, expected: "Show - #1071 - The Return"},
+		"anime no absolute":  {data: Data{Series: &Series{Title: "Show", Type: "anime"}, Episodes: []Episode{{SeasonNumber: 1, EpisodeNumber: 2, Title: "Two"}}}, expected: "Show - 1x02 - Two"},
+		"anime multi":        {data: Data{Series: &Series{Title: "Show", Type: "anime"}, Episodes: []Episode{ep, {SeasonNumber: 21, EpisodeNumber: 4, AbsoluteEpisodeNumber: 1072}}}, expected: "Show - #1071–1072"},
+		"daily without date": {data: Data{Series: &Series{Title: "Show", Type: "daily"}, Episodes: []Episode{{SeasonNumber: 1, EpisodeNumber: 2, Title: "Two"}}}, expected: "Show - 1x02 - Two"},
+	}
+
+	for name, tc := range tests {
+		assert.Equal(t, tc.expected, tc.data.Title(), name)
+	}
+}
+
+func TestSetFormats(t *testing.T) {
+	previous := formats["anime"]
+	defer SetFormats(map[string]string{"anime": previous})
+
+	SetFormats(map[string]string{"anime": "{series} {absolute} (S{season}E{episode})"})
+	d := Data{Series: &Series{Title: "Show", Type: "anime"}, Episodes: []Episode{{SeasonNumber: 21, EpisodeNumber: 3, AbsoluteEpisodeNumber: 1071}}}
+	assert.Equal(t, "Show 1071 (S21E03)", d.Title())
+}
+
+func TestSeasonPack(t *testing.T) {
+	episodes := []Episode{{SeasonNumber: 3, EpisodeNumber: 1}, {SeasonNumber: 3, EpisodeNumber: 2}}
+
+	tests := map[string]struct {
+		data     Data
+		expected bool
+	}{
+		"season pack":  {data: Data{EventType: arrhook.EventGrab, Episodes: episodes, Release: &Release{ReleaseTitle: "Show.S03.1080p"}}, expected: true},
+		"multi ep":     {data: Data{EventType: arrhook.EventGrab, Episodes: episodes, Release: &Release{ReleaseTitle: "Show.S03E01-E02.1080p"}}, expected: false},
+		"single":       {data: Data{EventType: arrhook.EventGrab, Episodes: episodes[:1], Release: &Release{ReleaseTitle: "Show.S03.1080p"}}, expected: false},
+		"download":     {data: Data{EventType: arrhook.EventDownload, Episodes: episodes, Release: &Release{ReleaseTitle: "Show.S03.1080p"}}, expected: false},
+		"many seasons": {data: Data{EventType: arrhook.EventGrab, Episodes: append(episodes, Episode{SeasonNumber: 4, EpisodeNumber: 1}), Release: &Release{ReleaseTitle: "Show.S03-S04.1080p"}}, expected: false},
+	}
+
+	for name, tc := range tests {
+		assert.Equal(t, tc.expected, tc.data.SeasonPack(), name)
+	}
+}
+
+// fixture parses a webhook saved from a version of Sonarr. They are kept
+// with arrhook, which parses them for gwarr
+func fixture(t *testing.T, name string) *Data {
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := ParseWebhook(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestVersionsRenderTheSame(t *testing.T) {
+	for _, name := range []string{"grab.json", "download.json"} {
+		v3, v4 := fixture(t, "v3/"+name), fixture(t, "v4/"+name)
+
+		assert.Equal(t, v3.Event().Kind, v4.Event().Kind, name)
+		assert.Equal(t, v3.ID(), v4.ID(), name)
+		assert.Equal(t, v3.Title(), v4.Title(), name)
+		assert.Equal(t, v3.URL(), v4.URL(), name)
+		assert.Equal(t, v3.Event().Quality(), v4.Event().Quality(), name)
+		assert.Equal(t, v3.Event().ReleaseGroup(), v4.Event().ReleaseGroup(), name)
+		assert.Equal(t, v3.Event().Size(), v4.Event().Size(), name)
+		assert.Equal(t, v3.Event().Subject.Episodes, v4.Event().Subject.Episodes, name)
+		assert.Equal(t, v3.DownloadID, v4.DownloadID, name)
+	}
+}
+
+func TestV4Fields(t *testing.T) {
+	grab := fixture(t, "v4/grab.json")
+	assert.Equal(t, []string{"English"}, grab.Languages())
+	assert.Nil(t, grab.ImportPaths())
+
+	download := fixture(t, "v4/download.json")
+	assert.Equal(t, []string{"English"}, download.Languages())
+	assert.Equal(t, &arr.ImportPaths{
+		Source:      "/downloads/complete/The.Expanse.S03E04.1080p.WEB-DL.NTb/the.expanse.s03e04.mkv",
+		Destination: "/tv/The Expanse/Season 3/The Expanse - S03E04 - Reload.mkv",
+	}, download.ImportPaths())
+
+	v3 := fixture(t, "v3/download.json")
+	assert.Empty(t, v3.Languages())
+	assert.Nil(t, v3.ImportPaths())
+}
+
+func TestImportComplete(t *testing.T) {
+	d := fixture(t, "v4/import_complete.json")
+
+	assert.Equal(t, arrhook.EventDownload, d.Event().Kind)
+	assert.Equal(t, "The Expanse - Season 4 (2 episodes)", d.Title())
+	assert.Equal(t, "WEBDL-2160p", d.Event().Quality())
+	assert.Equal(t, "NTb", d.Event().ReleaseGroup())
+	assert.Equal(t, 8589934592, d.Event().Size())
+	assert.Equal(t, []string{"English", "Spanish"}, d.Languages())
+	assert.Equal(t, &arr.ImportPaths{Source: "/downloads/complete/The.Expanse.S04.2160p.WEB-DL.NTb", Destination: "/tv/The Expanse/Season 4"}, d.ImportPaths())
+	assert.Len(t, d.Event().Subject.Episodes, 2)
+}
+
+func TestReleaseType(t *testing.T) {
+	episodes := []Episode{{SeasonNumber: 1, EpisodeNumber: 1}, {SeasonNumber: 1, EpisodeNumber: 2}}
+
+	tests := map[string]struct {
+		release  Release
+		expected bool
+	}{
+		"season pack": {release: Release{ReleaseTitle: "Show.S01E01-E02.1080p", ReleaseType: "seasonPack"}, expected: true},
+		"multi ep":    {release: Release{ReleaseTitle: "Show.S01.1080p", ReleaseType: "multiEpisode"}, expected: false},
+		"unknown":     {release: Release{ReleaseTitle: "Show.S01.1080p", ReleaseType: "unknown"}, expected: true},
+		"v3":          {release: Release{ReleaseTitle: "Show.S01E01-E02.1080p"}, expected: false},
+	}
+
+	for name, tc := range tests {
+		d := Data{EventType: arrhook.EventGrab, Episodes: episodes, Release: &tc.release}
+		assert.Equal(t, tc.expected, d.SeasonPack(), name)
+	}
+}
+
+func TestEvent(t *testing.T) {
+	v3, v4 := fixture(t, "v3/download.json").Event(), fixture(t, "v4/download.json").Event()
+
+	assert.Equal(t, "sonarr", v4.Source)
+	assert.Equal(t, "Sonarr", v4.Instance)
+	assert.Equal(t, arrhook.EventDownload, v4.Kind)
+	assert.Equal(t, v3.Title, v4.Title)
+	assert.Equal(t, v3.Subject.Episodes, v4.Subject.Episodes)
+	assert.Nil(t, v3.Release)
+	assert.Len(t, v3.Files, 1)
+
+	assert.Equal(t, v4.URL+"#season-3", v4.DeepURL)
+
+	e := fixture(t, "v4/import_complete.json").Event()
+	assert.Equal(t, arrhook.EventDownload, e.Kind)
+	assert.Equal(t, "Download", e.Type())
+	assert.True(t, e.ImportComplete())
+	assert.False(t, v4.ImportComplete())
+	assert.Equal(t, &arr.ImportPaths{Source: "/downloads/complete/The.Expanse.S04.2160p.WEB-DL.NTb", Destination: "/tv/The Expanse/Season 4"}, e.ImportPaths)
+	assert.Equal(t, 7, e.Subject.ID)
+	assert.Equal(t, "The.Expanse.S04.2160p.WEB-DL.NTb", e.Release.ReleaseTitle)
+	assert.Len(t, e.Files, 2)
+}
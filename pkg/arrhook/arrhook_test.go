@@ -0,0 +1,129 @@
+package arrhook
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventKind(t *testing.T) {
+	for k := EventTest; k <= EventManualInteractionRequired; k++ {
+		assert.Equal(t, k, ParseEventKind(k.String()))
+
+		b, err := json.Marshal(k)
+		assert.NoError(t, err)
+
+		var parsed EventKind
+		assert.NoError(t, json.Unmarshal(b, &parsed))
+		assert.Equal(t, k, parsed)
+	}
+
+	assert.Equal(t, EventUnknown, ParseEventKind("Teleport"))
+	assert.Equal(t, "Unknown", EventKind(99).String())
+}
+
+func TestParseRadarr(t *testing.T) {
+	r, err := ParseRadarr([]byte(`{
+		"eventType": "Download",
+		"instanceName": "Radarr",
+		"movie": {"id": 686, "title": "Film", "year": 1970, "tags": ["4k", 3]},
+		"movieFile": {"quality": "WEBDL-1080p", "releaseGroup": "legit", "mediaInfo": {"videoCodec": "x265", "audioChannels": 5.1}},
+		"deletedFiles": [{"quality": "HDTV-720p"}],
+		"isUpgrade": true
+	}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, EventDownload, r.Kind())
+	assert.Equal(t, "radarr", r.Service())
+	assert.Equal(t, "Radarr", r.Instance())
+	assert.Equal(t, Tags{"4k", "3"}, r.Movie.Tags)
+	assert.Equal(t, "x265", r.MovieFile.MediaInfo.VideoCodec)
+	assert.Equal(t, "HDTV-720p", r.DeletedFiles.Files[0].Quality)
+	assert.Nil(t, r.Health())
+}
+
+func TestParseSonarr(t *testing.T) {
+	for _, name := range []string{"v3/grab.json", "v4/grab.json", "v3/download.json", "v4/download.json", "v4/import_complete.json"} {
+		b, err := os.ReadFile(filepath.Join("testdata", "sonarr", name))
+		assert.NoError(t, err)
+
+		s, err := ParseSonarr(b)
+		assert.NoError(t, err, name)
+		assert.Equal(t, 7, s.Series.ID, name)
+		assert.NotEqual(t, EventUnknown, s.Kind(), name)
+	}
+
+	s, err := ParseSonarr([]byte(`{"eventType": "ImportComplete", "series": {"id": 1}, "episodeFiles": [{"id": 1}, {"id": 2}], "sourcePath": "/downloads/a"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, EventImportComplete, s.Kind())
+	assert.Len(t, s.EpisodeFiles, 2)
+	assert.Equal(t, "/downloads/a", s.SourcePath)
+}
+
+func TestParseFlat(t *testing.T) {
+	r, err := ParseRadarr([]byte(`{"eventType": "HealthRestored", "level": "warning", "message": "Indexers unavailable", "type": "IndexerStatusCheck"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "IndexerStatusCheck", r.HealthType)
+	assert.Equal(t, &Health{Level: "warning", Message: "Indexers unavailable", Type: "IndexerStatusCheck", EventType: "HealthRestored"}, r.Health())
+	assert.Nil(t, r.Update())
+
+	s, err := ParseSonarr([]byte(`{"eventType": "ApplicationUpdate", "previousVersion": "4.0.0", "newVersion": "4.0.1", "message": "Updated"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &Update{PreviousVersion: "4.0.0", NewVersion: "4.0.1", Message: "Updated", EventType: "ApplicationUpdate"}, s.Update())
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string][]byte{
+		"malformed":  []byte("}"),
+		"no movie":   []byte(`{"eventType": "Grab"}`),
+		"no check":   []byte(`{"eventType": "Health"}`),
+		"no version": []byte(`{"eventType": "ApplicationUpdate"}`),
+		"empty":      []byte(`{}`),
+		"null":       []byte(`null`),
+		"no type":    []byte(`{"foo": 1}`),
+		"blank type": []byte(`{"eventType": ""}`),
+	}
+
+	for name, body := range tests {
+		_, err := ParseRadarr(body)
+		assert.EqualError(t, err, "Unable to parse webhook", name)
+		_, err = ParseSonarr(body)
+		assert.EqualError(t, err, "Unable to parse webhook", name)
+	}
+
+	r, err := ParseRadarr([]byte(`{"eventType": "MovieTeleported"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, EventUnknown, r.Kind())
+	assert.Equal(t, "MovieTeleported", r.EventName)
+}
+
+func TestSwitch(t *testing.T) {
+	var called string
+	handlers := map[EventKind]HandlerFunc{
+		EventGrab: func(p Payload) error {
+			called = "grab " + p.(*Sonarr).Series.Title
+			return nil
+		},
+		EventDownload: func(Payload) error { return errors.New("failed") },
+	}
+	fallback := func(p Payload) error {
+		called = "fallback " + p.Kind().String()
+		return nil
+	}
+
+	assert.NoError(t, Switch(&Sonarr{EventType: EventGrab, Series: &Series{Title: "Show"}}, handlers, fallback))
+	assert.Equal(t, "grab Show", called)
+
+	assert.EqualError(t, Switch(&Radarr{EventType: EventDownload}, handlers, fallback), "failed")
+
+	assert.NoError(t, Switch(&Radarr{EventType: EventRename}, handlers, fallback))
+	assert.Equal(t, "fallback Rename", called)
+
+	called = ""
+	assert.NoError(t, Switch(&Radarr{EventType: EventRename}, handlers, nil))
+	assert.Empty(t, called)
+}
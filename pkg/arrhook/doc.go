@@ -0,0 +1,38 @@
+/*
+Package arrhook parses the webhooks sent by Radarr and Sonarr into typed
+structs.
+
+	p, err := arrhook.ParseSonarr(body)
+	if err != nil {
+		return err
+	}
+
+	return arrhook.Switch(p, map[arrhook.EventKind]arrhook.HandlerFunc{
+		arrhook.EventGrab:     onGrab,
+		arrhook.EventDownload: onDownload,
+	}, nil)
+
+# Compatibility
+
+arrhook follows semantic versioning on its own Version, separate from
+gwarr's releases. Within a major version:
+
+  - Nothing exported is removed or renamed, and no signature changes
+  - Fields, EventKind constants and functions can be added, as the *arrs
+    add them
+  - EventKind values keep their number, so stored kinds stay valid
+  - Event types arrhook doesn't know parse as EventUnknown, with their name
+    in EventName, rather than failing. A newer *arr doesn't break an older
+    arrhook
+  - A webhook without an eventType is always a ParseError
+
+Anything else, including a known type starting to fail to parse, is a new
+major version.
+
+Fields that only some versions of an *arr send are documented as such,
+and are left empty when they aren't sent.
+*/
+package arrhook
+
+// Version is arrhook's semantic version, see Compatibility
+const Version = "1.0.0"
@@ -0,0 +1,83 @@
+package arrhook
+
+import (
+	"encoding/json"
+)
+
+// EventKind defines the type of event a webhook is about
+type EventKind int
+
+// The kinds of event sent by Radarr and Sonarr. New kinds are only ever
+// added at the end, so the values are stable
+const (
+	EventUnknown EventKind = iota
+	EventTest
+	EventGrab
+	EventDownload
+	EventImportComplete
+	EventRename
+	EventMovieAdded
+	EventMovieDelete
+	EventMovieFileDelete
+	EventSeriesAdd
+	EventSeriesDelete
+	EventEpisodeFileDelete
+	EventHealth
+	EventHealthRestored
+	EventApplicationUpdate
+	EventManualInteractionRequired
+)
+
+var kindNames = map[EventKind]string{
+	EventTest:                      "Test",
+	EventGrab:                      "Grab",
+	EventDownload:                  "Download",
+	EventImportComplete:            "ImportComplete",
+	EventRename:                    "Rename",
+	EventMovieAdded:                "MovieAdded",
+	EventMovieDelete:               "MovieDelete",
+	EventMovieFileDelete:           "MovieFileDelete",
+	EventSeriesAdd:                 "SeriesAdd",
+	EventSeriesDelete:              "SeriesDelete",
+	EventEpisodeFileDelete:         "EpisodeFileDelete",
+	EventHealth:                    "Health",
+	EventHealthRestored:            "HealthRestored",
+	EventApplicationUpdate:         "ApplicationUpdate",
+	EventManualInteractionRequired: "ManualInteractionRequired",
+}
+
+// ParseEventKind returns the kind for an *arr's eventType, or EventUnknown
+func ParseEventKind(s string) EventKind {
+	for k, name := range kindNames {
+		if name == s {
+			return k
+		}
+	}
+	return EventUnknown
+}
+
+// String returns the eventType the *arrs send for the kind
+func (k EventKind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// UnmarshalJSON reads an eventType. Types arrhook doesn't know are
+// EventUnknown, and their name is kept as the payload's EventName
+func (k *EventKind) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	*k = ParseEventKind(s)
+	return nil
+}
+
+// MarshalJSON writes the kind as the eventType the *arrs send
+func (k EventKind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.String())
+}
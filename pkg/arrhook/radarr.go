@@ -0,0 +1,95 @@
+package arrhook
+
+import (
+	"encoding/json"
+)
+
+// Radarr defines every field of a Radarr webhook. Which are set depends
+// on the event
+type Radarr struct {
+	EventType      EventKind `json:"eventType"`
+	InstanceName   string    `json:"instanceName,omitempty"`
+	ApplicationURL string    `json:"applicationUrl,omitempty"`
+
+	// EventName is the eventType as it was sent, which is all there is
+	// to go on for an EventUnknown
+	EventName string `json:"-"`
+
+	Movie       *Movie       `json:"movie,omitempty"`
+	RemoteMovie *RemoteMovie `json:"remoteMovie,omitempty"`
+
+	// Grab and Download
+	Release            *Release          `json:"release,omitempty"`
+	CustomFormatInfo   *CustomFormatInfo `json:"customFormatInfo,omitempty"`
+	DownloadClient     string            `json:"downloadClient,omitempty"`
+	DownloadClientType string            `json:"downloadClientType,omitempty"`
+	DownloadID         string            `json:"downloadId,omitempty"`
+
+	// Download, MovieFileDelete and MovieDelete
+	MovieFile    *File        `json:"movieFile,omitempty"`
+	IsUpgrade    bool         `json:"isUpgrade,omitempty"`
+	DeletedFiles DeletedFiles `json:"deletedFiles,omitempty"`
+	DeleteReason string       `json:"deleteReason,omitempty"`
+
+	// Rename
+	RenamedMovieFiles []RenamedFile `json:"renamedMovieFiles,omitempty"`
+
+	// ManualInteractionRequired
+	DownloadInfo   *DownloadInfo   `json:"downloadInfo,omitempty"`
+	DownloadStatus string          `json:"downloadStatus,omitempty"`
+	StatusMessages []StatusMessage `json:"downloadStatusMessages,omitempty"`
+
+	// Health and HealthRestored. HealthType is the check, like
+	// "IndexerStatusCheck"
+	Level      string `json:"level,omitempty"`
+	HealthType string `json:"type,omitempty"`
+	WikiURL    string `json:"wikiUrl,omitempty"`
+
+	// Health, HealthRestored and ApplicationUpdate
+	Message string `json:"message,omitempty"`
+
+	// ApplicationUpdate
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	NewVersion      string `json:"newVersion,omitempty"`
+}
+
+// ParseRadarr parses a Radarr webhook. Events about a movie must say
+// which movie
+func ParseRadarr(body []byte) (*Radarr, error) {
+	name, ok := eventName(body)
+	if !ok {
+		return nil, &ParseError{}
+	}
+
+	r := Radarr{EventName: name}
+	err := json.Unmarshal(body, &r)
+	if err != nil || !valid(r.EventType, r.Movie != nil && r.Movie.ID != 0, r.HealthType, r.NewVersion) {
+		return nil, &ParseError{}
+	}
+	return &r, nil
+}
+
+// Kind returns the type of event the webhook is about
+func (r *Radarr) Kind() EventKind { return r.EventType }
+
+// Service returns "radarr"
+func (r *Radarr) Service() string { return "radarr" }
+
+// Instance returns the name of the instance that sent the webhook
+func (r *Radarr) Instance() string { return r.InstanceName }
+
+// Health returns the health check for Health and HealthRestored events
+func (r *Radarr) Health() *Health {
+	if r.EventType != EventHealth && r.EventType != EventHealthRestored {
+		return nil
+	}
+	return &Health{Level: r.Level, Message: r.Message, Type: r.HealthType, WikiURL: r.WikiURL, EventType: r.EventType.String()}
+}
+
+// Update returns the versions for ApplicationUpdate events
+func (r *Radarr) Update() *Update {
+	if r.EventType != EventApplicationUpdate {
+		return nil
+	}
+	return &Update{PreviousVersion: r.PreviousVersion, NewVersion: r.NewVersion, Message: r.Message, EventType: r.EventType.String()}
+}
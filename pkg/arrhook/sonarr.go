@@ -0,0 +1,101 @@
+package arrhook
+
+import (
+	"encoding/json"
+)
+
+// Sonarr defines every field of a Sonarr webhook. Which are set depends
+// on the event
+type Sonarr struct {
+	EventType      EventKind `json:"eventType"`
+	InstanceName   string    `json:"instanceName,omitempty"`
+	ApplicationURL string    `json:"applicationUrl,omitempty"`
+
+	// EventName is the eventType as it was sent, which is all there is
+	// to go on for an EventUnknown
+	EventName string `json:"-"`
+
+	Series   *Series   `json:"series,omitempty"`
+	Episodes []Episode `json:"episodes,omitempty"`
+
+	// Grab, Download and ImportComplete
+	Release            *Release          `json:"release,omitempty"`
+	CustomFormatInfo   *CustomFormatInfo `json:"customFormatInfo,omitempty"`
+	DownloadClient     string            `json:"downloadClient,omitempty"`
+	DownloadClientType string            `json:"downloadClientType,omitempty"`
+	DownloadID         string            `json:"downloadId,omitempty"`
+
+	// Download, EpisodeFileDelete and SeriesDelete
+	EpisodeFile  *File        `json:"episodeFile,omitempty"`
+	IsUpgrade    bool         `json:"isUpgrade,omitempty"`
+	DeletedFiles DeletedFiles `json:"deletedFiles,omitempty"`
+	DeleteReason string       `json:"deleteReason,omitempty"`
+
+	// ImportComplete, which is only sent by Sonarr v4
+	EpisodeFiles    []File `json:"episodeFiles,omitempty"`
+	FileCount       int    `json:"fileCount,omitempty"`
+	SourcePath      string `json:"sourcePath,omitempty"`
+	DestinationPath string `json:"destinationPath,omitempty"`
+
+	// Rename
+	RenamedEpisodeFiles []RenamedFile `json:"renamedEpisodeFiles,omitempty"`
+
+	// ManualInteractionRequired
+	DownloadInfo   *DownloadInfo   `json:"downloadInfo,omitempty"`
+	DownloadStatus string          `json:"downloadStatus,omitempty"`
+	StatusMessages []StatusMessage `json:"downloadStatusMessages,omitempty"`
+
+	// Health and HealthRestored. HealthType is the check, like
+	// "IndexerStatusCheck"
+	Level      string `json:"level,omitempty"`
+	HealthType string `json:"type,omitempty"`
+	WikiURL    string `json:"wikiUrl,omitempty"`
+
+	// Health, HealthRestored and ApplicationUpdate
+	Message string `json:"message,omitempty"`
+
+	// ApplicationUpdate
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	NewVersion      string `json:"newVersion,omitempty"`
+}
+
+// ParseSonarr parses a Sonarr webhook. Events about a series must say
+// which series
+func ParseSonarr(body []byte) (*Sonarr, error) {
+	name, ok := eventName(body)
+	if !ok {
+		return nil, &ParseError{}
+	}
+
+	s := Sonarr{EventName: name}
+	err := json.Unmarshal(body, &s)
+	if err != nil || !valid(s.EventType, s.Series != nil && s.Series.ID != 0, s.HealthType, s.NewVersion) {
+		return nil, &ParseError{}
+	}
+	return &s, nil
+}
+
+// Kind returns the type of event the webhook is about
+func (s *Sonarr) Kind() EventKind { return s.EventType }
+
+// Service returns "sonarr"
+func (s *Sonarr) Service() string { return "sonarr" }
+
+// Instance returns the name of the instance that sent the webhook
+func (s *Sonarr) Instance() string { return s.InstanceName }
+
+// Health returns the health check for Health and HealthRestored events
+func (s *Sonarr) Health() *Health {
+	if s.EventType != EventHealth && s.EventType != EventHealthRestored {
+		return nil
+	}
+	return &Health{Level: s.Level, Message: s.Message, Type: s.HealthType, WikiURL: s.WikiURL, EventType: s.EventType.String()}
+}
+
+// Update returns the versions for ApplicationUpdate events
+func (s *Sonarr) Update() *Update {
+	if s.EventType != EventApplicationUpdate {
+		return nil
+	}
+	return &Update{PreviousVersion: s.PreviousVersion, NewVersion: s.NewVersion, Message: s.Message, EventType: s.EventType.String()}
+}
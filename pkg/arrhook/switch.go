@@ -0,0 +1,24 @@
+package arrhook
+
+// Payload is implemented by the webhooks of every *arr
+type Payload interface {
+	Kind() EventKind
+	Service() string
+	Instance() string
+}
+
+// HandlerFunc handles a webhook. Type switch on the payload to reach the
+// fields of a particular *arr
+type HandlerFunc func(Payload) error
+
+// Switch calls the handler for the payload's kind. Kinds without a
+// handler go to fallback, and are ignored if fallback is nil
+func Switch(p Payload, handlers map[EventKind]HandlerFunc, fallback HandlerFunc) error {
+	if h, ok := handlers[p.Kind()]; ok && h != nil {
+		return h(p)
+	}
+	if fallback != nil {
+		return fallback(p)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package arrhook
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ParseError is returned when a webhook can't be parsed
+type ParseError struct{}
+
+func (pe *ParseError) Error() string {
+	return "Unable to parse webhook"
+}
+
+// eventName returns the eventType of a webhook. Every webhook has one, so
+// a body without it isn't from an *arr
+func eventName(body []byte) (string, bool) {
+	var head struct {
+		EventType string `json:"eventType"`
+	}
+	err := json.Unmarshal(body, &head)
+	if err != nil || head.EventType == "" {
+		return "", false
+	}
+	return head.EventType, true
+}
+
+// valid checks a webhook has what its kind needs. Events about a movie or
+// series need to say which, and the rest need their own fields
+func valid(k EventKind, subject bool, check string, version string) bool {
+	switch k {
+	case EventHealth, EventHealthRestored:
+		return check != ""
+	case EventApplicationUpdate:
+		return version != ""
+	case EventTest, EventManualInteractionRequired, EventUnknown:
+		return true
+	}
+	return subject
+}
+
+// Release defines metadata about a grabbed release. Languages and
+// ReleaseType are only sent by Sonarr v4
+type Release struct {
+	Quality        string     `json:"quality,omitempty"`
+	QualityVersion int        `json:"qualityVersion,omitempty"`
+	ReleaseGroup   string     `json:"releaseGroup,omitempty"`
+	ReleaseTitle   string     `json:"releaseTitle,omitempty"`
+	Indexer        string     `json:"indexer,omitempty"`
+	Size           int        `json:"size,omitempty"`
+	Languages      []Language `json:"languages,omitempty"`
+	ReleaseType    string     `json:"releaseType,omitempty"`
+}
+
+// File defines metadata about a local media file
+type File struct {
+	ID             int        `json:"id,omitempty"`
+	RelativePath   string     `json:"relativePath,omitempty"`
+	Path           string     `json:"path,omitempty"`
+	Quality        string     `json:"quality,omitempty"`
+	QualityVersion int        `json:"qualityVersion,omitempty"`
+	ReleaseGroup   string     `json:"releaseGroup,omitempty"`
+	SceneName      string     `json:"sceneName,omitempty"`
+	IndexerFlags   string     `json:"indexerFlags,omitempty"`
+	Size           int        `json:"size,omitempty"`
+	DateAdded      string     `json:"dateAdded,omitempty"`
+	Languages      []Language `json:"languages,omitempty"`
+	MediaInfo      *MediaInfo `json:"mediaInfo,omitempty"`
+	SourcePath     string     `json:"sourcePath,omitempty"`
+}
+
+// RenamedFile defines a file and the path it had before it was renamed
+type RenamedFile struct {
+	PreviousRelativePath string `json:"previousRelativePath,omitempty"`
+	PreviousPath         string `json:"previousPath,omitempty"`
+	ID                   int    `json:"id,omitempty"`
+	RelativePath         string `json:"relativePath,omitempty"`
+	Path                 string `json:"path,omitempty"`
+	Quality              string `json:"quality,omitempty"`
+	QualityVersion       int    `json:"qualityVersion,omitempty"`
+	ReleaseGroup         string `json:"releaseGroup,omitempty"`
+	SceneName            string `json:"sceneName,omitempty"`
+	IndexerFlags         string `json:"indexerFlags,omitempty"`
+	Size                 int    `json:"size,omitempty"`
+}
+
+// MediaInfo defines what is in a media file
+type MediaInfo struct {
+	AudioChannels         float64  `json:"audioChannels,omitempty"`
+	AudioCodec            string   `json:"audioCodec,omitempty"`
+	AudioLanguages        []string `json:"audioLanguages,omitempty"`
+	Height                int      `json:"height,omitempty"`
+	Width                 int      `json:"width,omitempty"`
+	Subtitles             []string `json:"subtitles,omitempty"`
+	VideoCodec            string   `json:"videoCodec,omitempty"`
+	VideoDynamicRange     string   `json:"videoDynamicRange,omitempty"`
+	VideoDynamicRangeType string   `json:"videoDynamicRangeType,omitempty"`
+}
+
+// Language defines a language of a release or file
+type Language struct {
+	ID   int    `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// Tags defines the tags on a movie or series. Older *arrs send tag IDs
+// rather than labels, so those are kept as their number
+type Tags []string
+
+// UnmarshalJSON reads a list of tag labels or tag IDs
+func (t *Tags) UnmarshalJSON(b []byte) error {
+	var raw []any
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+
+	*t = Tags{}
+	for _, v := range raw {
+		switch tag := v.(type) {
+		case string:
+			*t = append(*t, tag)
+		case float64:
+			*t = append(*t, fmt.Sprint(tag))
+		default:
+			return fmt.Errorf("invalid tag %v", v)
+		}
+	}
+
+	return nil
+}
+
+// Movie defines a movie
+type Movie struct {
+	ID          int    `json:"id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Year        int    `json:"year,omitempty"`
+	ReleaseDate string `json:"releaseDate,omitempty"`
+	FolderPath  string `json:"folderPath,omitempty"`
+	TMDBID      int    `json:"tmdbId,omitempty"`
+	IMDBID      string `json:"imdbId,omitempty"`
+	Tags        Tags   `json:"tags,omitempty"`
+}
+
+// RemoteMovie defines what an indexer said a release was a movie of
+type RemoteMovie struct {
+	TMDBID int    `json:"tmdbId,omitempty"`
+	IMDBID string `json:"imdbId,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Year   int    `json:"year,omitempty"`
+}
+
+// Series defines a series
+type Series struct {
+	ID        int      `json:"id,omitempty"`
+	Title     string   `json:"title,omitempty"`
+	TitleSlug string   `json:"titleSlug,omitempty"`
+	Path      string   `json:"path,omitempty"`
+	TVDBID    int      `json:"tvdbId,omitempty"`
+	TVMazeID  int      `json:"tvMazeId,omitempty"`
+	IMDBID    string   `json:"imdbId,omitempty"`
+	Type      string   `json:"type,omitempty"`
+	Tags      Tags     `json:"tags,omitempty"`
+	Year      int      `json:"year,omitempty"`
+	Network   string   `json:"network,omitempty"`
+	Monitored *bool    `json:"monitored,omitempty"`
+	Seasons   []Season `json:"seasons,omitempty"`
+}
+
+// Season defines a season of a series
+type Season struct {
+	SeasonNumber int  `json:"seasonNumber"`
+	Monitored    bool `json:"monitored"`
+}
+
+// Episode defines an episode of a series
+type Episode struct {
+	ID                    int    `json:"id,omitempty"`
+	EpisodeNumber         int    `json:"episodeNumber,omitempty"`
+	SeasonNumber          int    `json:"seasonNumber,omitempty"`
+	AbsoluteEpisodeNumber int    `json:"absoluteEpisodeNumber,omitempty"`
+	Title                 string `json:"title,omitempty"`
+	AirDate               string `json:"airDate,omitempty"`
+	AirDateUTC            string `json:"airDateUtc,omitempty"`
+}
+
+// DeletedFiles is sent as a bool when a movie or series is deleted, saying
+// whether the files were deleted too, and as the replaced files on an
+// upgrade Download
+type DeletedFiles struct {
+	Deleted bool
+	Files   []File
+}
+
+// UnmarshalJSON parses either form of deletedFiles
+func (df *DeletedFiles) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &df.Deleted); err == nil {
+		return nil
+	}
+	return json.Unmarshal(b, &df.Files)
+}
+
+// MarshalJSON writes deletedFiles in the form it was received
+func (df DeletedFiles) MarshalJSON() ([]byte, error) {
+	if df.Files != nil {
+		return json.Marshal(df.Files)
+	}
+	return json.Marshal(df.Deleted)
+}
+
+// Health defines information about an *arr health issue
+type Health struct {
+	Level     string `json:"level,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Type      string `json:"type,omitempty"`
+	WikiURL   string `json:"wikiUrl,omitempty"`
+	EventType string `json:"eventType,omitempty"`
+}
+
+// Update defines information about an *arr updating itself
+type Update struct {
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	NewVersion      string `json:"newVersion,omitempty"`
+	Message         string `json:"message,omitempty"`
+	EventType       string `json:"eventType,omitempty"`
+}
+
+// CustomFormatInfo defines the custom formats a release matched
+type CustomFormatInfo struct {
+	CustomFormats     []CustomFormat `json:"customFormats,omitempty"`
+	CustomFormatScore int            `json:"customFormatScore"`
+}
+
+// CustomFormat defines a custom format set up in the *arr
+type CustomFormat struct {
+	ID   int    `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// DownloadInfo defines the download client's view of a release
+type DownloadInfo struct {
+	Quality        string `json:"quality,omitempty"`
+	QualityVersion int    `json:"qualityVersion,omitempty"`
+	Title          string `json:"title,omitempty"`
+	Size           int    `json:"size,omitempty"`
+}
+
+// StatusMessage defines why a download can't be imported
+type StatusMessage struct {
+	Title    string   `json:"title,omitempty"`
+	Messages []string `json:"messages,omitempty"`
+}